@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	jlang "github.com/jntun/mylang/lang"
+	"io/ioutil"
+	"strings"
+)
+
+// dumpAST is 'jlang -ast[=json|sexp] file', it prints the parsed program of the file without running it.
+// S-expressions are the default.
+func dumpAST(flag string, args []string) int {
+	format := strings.TrimPrefix(strings.TrimPrefix(flag, "-ast"), "=")
+	if len(args) != 1 || (format != "" && format != "sexp" && format != "json") {
+		fmt.Println("usage: jlang -ast[=json|sexp] <file>")
+		return 2
+	}
+
+	text, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	program, err := jlang.ParseSource(&jlang.Source{Name: args[0], Text: string(text)})
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if format == "json" {
+		out, err := jlang.DumpJSON(program)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		fmt.Println(out)
+		return 0
+	}
+	fmt.Println(jlang.DumpSexp(program))
+	return 0
+}
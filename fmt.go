@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	jlang "github.com/jntun/mylang/lang"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// formatFiles is 'jlang fmt [--check] paths...', it formats each file in place or with --check only lists
+// the ones that aren't formatted. Directories are formatted for every .jlang file in them.
+func formatFiles(args []string) int {
+	check := false
+	paths := make([]string, 0)
+	for _, arg := range args {
+		if arg == "--check" || arg == "-check" {
+			check = true
+			continue
+		}
+		paths = append(paths, arg)
+	}
+	if len(paths) == 0 {
+		fmt.Println("usage: jlang fmt [--check] <file or directory>...")
+		return 2
+	}
+
+	status := 0
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || (file != path && filepath.Ext(file) != ".jlang") {
+				return nil
+			}
+
+			formatted, changed, err := formatFile(file)
+			if err != nil {
+				fmt.Printf("%s: %s\n", file, err)
+				status = 1
+				return nil
+			}
+			if changed && check {
+				fmt.Println(file)
+				status = 1
+			}
+			if changed && !check {
+				return ioutil.WriteFile(file, []byte(formatted), info.Mode())
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// formatFile is the formatted file and whether that's different from what's there now
+func formatFile(file string) (string, bool, error) {
+	text, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", false, err
+	}
+	formatted, err := jlang.Format(&jlang.Source{Name: file, Text: string(text)})
+	if err != nil {
+		return "", false, err
+	}
+	return formatted, formatted != string(text), nil
+}
@@ -23,6 +23,7 @@ type FunctionDeclarationStatement struct {
 	args       *[]Token
 	arity      uint
 	block      []Statement
+	Doc        string // The comment directly above the declaration
 }
 
 type PropertyAssignmentStatement struct {
@@ -30,13 +31,15 @@ type PropertyAssignmentStatement struct {
 	value Expression
 }
 
-type ArrayDeclarationStatement struct {
-	Identifier Token
-	ExprList   []Expression
+// IndexAssignmentStatement is 'array[index] = value'
+type IndexAssignmentStatement struct {
+	get   ArrayAccess
+	value Expression
 }
 
 type AssignmentStatement struct {
 	VariableStatement
+	local *local // Filled in by the Resolver
 }
 
 type IfStatement struct {
@@ -57,6 +60,32 @@ type ForStatement struct {
 	block   []Statement
 }
 
+// BreakStatement stops the innermost loop it's in
+type BreakStatement struct {
+	keyword Token
+}
+
+// ContinueStatement skips to the next iteration of the innermost loop it's in
+type ContinueStatement struct {
+	keyword Token
+}
+
+// ThrowStatement raises its value as an error that an enclosing TryStatement can catch i.e 'throw Error("bad input");'
+type ThrowStatement struct {
+	keyword Token
+	Expression
+}
+
+// TryStatement runs block, and catchBlock with the error as catchParam if it throws. finallyBlock runs last
+// however the others finish, even on a 'return'. It has a catch, a finally, or both.
+type TryStatement struct {
+	keyword      Token
+	block        []Statement
+	catchParam   *Token
+	catchBlock   *[]Statement
+	finallyBlock *[]Statement
+}
+
 type ExpressionStatement struct {
 	Expression
 }
@@ -75,8 +104,16 @@ type Binary struct {
 	Right Expression
 }
 
+// Logical is a short-circuiting 'and' / 'or' expression
+type Logical struct {
+	Left  Expression
+	Op    Operator
+	Right Expression
+}
+
 type Grouping struct {
-	Expr Expression
+	Expr   Expression
+	parens Token // From the '(' through the ')'
 }
 type Unary struct {
 	Op   Operator
@@ -85,22 +122,26 @@ type Unary struct {
 
 type Variable struct {
 	identifier Token
+	local      *local // Filled in by the Resolver, a Variable that's made at run time leaves it nil
 }
 
+// Call is calling the Value of any expression i.e 'area(x, y)', 'Rect(1, 2)' or 'handlers[0](x)'
 type Call struct {
-	identifier Token
-	args       *[]Expression
+	callee Expression
+	paren  Token
+	args   *[]Expression
 }
 
-type FunctionCall struct {
-	identifier Token
-	args       *[]Expression
+// Lambda is an anonymous function expression i.e 'func (x) { return x * 2; }' or '(x) => x * 2'
+type Lambda struct {
+	decl  FunctionDeclarationStatement
+	start Token // The 'func' keyword, or the first token of an arrow function's parameters
 }
 
-type FunctionInvocation struct {
-	stmt     FunctionDeclarationStatement
-	argExprs *[]Expression
-	arity    uint
+// SuperAccess is a superclass method bound to 'this' i.e 'super.area', or the superclass constructor when method is nil i.e 'super(x, y)'
+type SuperAccess struct {
+	keyword Token
+	method  *Token
 }
 
 type MethodInvocation struct {
@@ -114,9 +155,32 @@ type PropertyAccess struct {
 	identifier Token
 }
 
+// ArrayLiteral is an array expression i.e '[1, 2, 3]' or '[[1, 2], [3, 4]]'
+type ArrayLiteral struct {
+	bracket  Token
+	elements []Expression
+	closing  Token
+}
+
+// MapLiteral is a map expression i.e '{"width": 5, "height": 2}'. keys and values are in pairs
+type MapLiteral struct {
+	brace   Token
+	keys    []Expression
+	values  []Expression
+	closing Token
+}
+
+// ArrayAccess is indexing the array or map of any expression i.e 'arr[0]', 'grid[i][j]', 'rect.sides()[1]' or 'ages["bob"]'
 type ArrayAccess struct {
-	identifier Token
-	index      Expression
+	Expr    Expression
+	index   Expression
+	bracket Token
+}
+
+// Interpolation is a string with embedded expressions i.e "area is ${r.area()}".
+// parts alternates between the string Literals and the expressions in between them.
+type Interpolation struct {
+	parts []Expression
 }
 
 type Operator struct{ Token }
@@ -124,6 +188,42 @@ type Operator struct{ Token }
 // A Literal is a number, string, boolean, or nil
 type Literal struct{ Token }
 
-// Value is the base atom for all derived jlang types
-// Different type(s) implementations are determined at run time
-type Value interface{}
+// spanOf is the source span an Expression was parsed from, used to point errors at the offending code.
+func spanOf(expr Expression) Token {
+	switch e := expr.(type) {
+	case Binary:
+		return spanOf(e.Left).through(spanOf(e.Right))
+	case Logical:
+		return spanOf(e.Left).through(spanOf(e.Right))
+	case Grouping:
+		return e.parens
+	case Unary:
+		return e.Op.Token.through(spanOf(e.Expr))
+	case Literal:
+		return e.Token
+	case Variable:
+		return e.identifier
+	case Interpolation:
+		return spanOf(e.parts[0]).through(spanOf(e.parts[len(e.parts)-1]))
+	case Call:
+		return spanOf(e.callee).through(e.paren)
+	case Lambda:
+		return e.start.through(e.decl.Identifier)
+	case SuperAccess:
+		if e.method != nil {
+			return e.keyword.through(*e.method)
+		}
+		return e.keyword
+	case MethodInvocation:
+		return spanOf(e.this).through(e.identifier)
+	case PropertyAccess:
+		return spanOf(e.Expr).through(e.identifier)
+	case ArrayLiteral:
+		return e.bracket.through(e.closing)
+	case MapLiteral:
+		return e.brace.through(e.closing)
+	case ArrayAccess:
+		return spanOf(e.Expr).through(e.bracket)
+	}
+	return Token{}
+}
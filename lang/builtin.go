@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"reflect"
 	"time"
+	"unicode/utf8"
 )
 
 type Len struct {
@@ -13,120 +13,273 @@ type Len struct {
 }
 
 func (l Len) evaluate(intptr *Interpreter) (Value, error) {
-	v, err := intptr.VariableResolver(Variable{Token{"v", Identifier, 0}})
+	v, err := intptr.VariableResolver(Variable{Token{Lexeme: "v", Type: Identifier}, nil})
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
 
-	if kind := reflect.TypeOf(v).Kind(); kind == reflect.Array || kind == reflect.Slice {
-		return len(v.([]*Value)) - 1, nil
-	} else if kind == reflect.String {
-		return len(v.(string)) - 1, nil
-	} else {
-		return 0, fmt.Errorf("type '%s' doesn't have len() implementation", kind)
+	switch v.kind {
+	case MapKind:
+		return intValue(len(v.asMap().keys)), nil
+	case ArrayKind:
+		return intValue(len(v.asArray())), nil
+	case StringKind:
+		return intValue(utf8.RuneCountInString(v.asString())), nil
 	}
+	return intValue(0), fmt.Errorf("type '%s' doesn't have len() implementation", v.kind)
 }
 
 type Time struct{}
 
 func (t Time) evaluate(intptr *Interpreter) (Value, error) {
-	return int(time.Now().UnixNano() / 1000000), nil
+	return intValue(int(time.Now().UnixNano() / 1000000)), nil
 }
 
 type Pow struct{}
 
 func (p Pow) evaluate(intptr *Interpreter) (Value, error) {
-	x, err := intptr.VariableResolver(Variable{Token{"x", Identifier, 0}})
+	x, err := intptr.VariableResolver(Variable{Token{Lexeme: "x", Type: Identifier}, nil})
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
-	y, err := intptr.VariableResolver(Variable{Token{"y", Identifier, 0}})
+	y, err := intptr.VariableResolver(Variable{Token{Lexeme: "y", Type: Identifier}, nil})
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
 
-	switch reflect.TypeOf(x).Kind() {
-	case reflect.Int:
-		if yKind := reflect.TypeOf(y).Kind(); yKind == reflect.Int {
-			return math.Pow(float64(x.(int)), float64(y.(int))), nil
-		} else if yKind == reflect.Float64 {
-			return math.Pow(float64(x.(int)), y.(float64)), nil
-		} else {
-			return nil, fmt.Errorf("invalid type '%s' in 'pow' call.", yKind)
-		}
-	case reflect.Float64:
-		if yKind := reflect.TypeOf(y).Kind(); yKind == reflect.Int {
-			return math.Pow(x.(float64), float64(y.(int))), nil
-		} else if yKind == reflect.Float64 {
-			return math.Pow(x.(float64), y.(float64)), nil
-		} else {
-			return nil, fmt.Errorf("invalid type '%s' in 'pow' call.", yKind)
-		}
+	base, ok := x.float()
+	if !ok {
+		return Value{}, fmt.Errorf("invalid type '%s' in 'pow' call", x.kind)
 	}
-
-	return nil, fmt.Errorf("invalid type '%s' in 'pow' call", reflect.TypeOf(x).Kind())
+	exponent, ok := y.float()
+	if !ok {
+		return Value{}, fmt.Errorf("invalid type '%s' in 'pow' call.", y.kind)
+	}
+	return numberValue(math.Pow(base, exponent)), nil
 }
 
 type Quit struct{}
 
 func (q Quit) evaluate(intptr *Interpreter) (Value, error) {
 	os.Exit(0)
-	return nil, nil // Unreachable
+	return Value{}, nil // Unreachable
 }
 
 type AppendBuiltin struct{}
 
 func (app AppendBuiltin) evaluate(intptr *Interpreter) (Value, error) {
-	var arr interface{}
-	s, err := intptr.VariableResolver(Variable{Token{"s", Identifier, 0}})
+	s, err := intptr.VariableResolver(Variable{Token{Lexeme: "s", Type: Identifier}, nil})
 	if err != nil {
-		return nil, err
+		return Value{}, err
+	}
+	if s.kind != ArrayKind {
+		return Value{}, fmt.Errorf("type '%s' is not appendable", s.kind)
+	}
+	v, err := intptr.VariableResolver(Variable{Token{Lexeme: "v", Type: Identifier}, nil})
+	if err != nil {
+		return Value{}, err
+	}
+
+	// The new array gets its own elements, so assigning into it doesn't change s
+	elems := make([]Value, len(s.asArray()), len(s.asArray())+1)
+	copy(elems, s.asArray())
+	return arrayValue(append(elems, v)), nil
+}
+
+type MapBuiltin struct{}
+
+// evaluate calls 'f' with each element of the array 's' and returns a new array of the results
+func (m MapBuiltin) evaluate(intptr *Interpreter) (Value, error) {
+	s, err := intptr.VariableResolver(Variable{Token{Lexeme: "s", Type: Identifier}, nil})
+	if err != nil {
+		return Value{}, err
+	}
+	if s.kind != ArrayKind {
+		return Value{}, fmt.Errorf("type '%s' is not mappable", s.kind)
 	}
-	if kind := reflect.TypeOf(s).Kind(); kind != reflect.Slice {
-		return nil, fmt.Errorf("type '%s' is not appendable", kind)
+	f, err := intptr.VariableResolver(Variable{Token{Lexeme: "f", Type: Identifier}, nil})
+	if err != nil {
+		return Value{}, err
 	}
-	v, err := intptr.VariableResolver(Variable{Token{"v", Identifier, 0}})
+
+	mapped := make([]Value, 0, len(s.asArray()))
+	for _, elem := range s.asArray() {
+		val, err := intptr.callValue(f, Token{Lexeme: "map", Type: Identifier}, []Value{elem})
+		if err != nil {
+			return Value{}, err
+		}
+		mapped = append(mapped, val)
+	}
+
+	return arrayValue(mapped), nil
+}
+
+// resolveMap resolves the map argument 'm' of a map builtin
+func resolveMap(intptr *Interpreter, builtin string) (*JlangMap, error) {
+	m, err := intptr.VariableResolver(Variable{Token{Lexeme: "m", Type: Identifier}, nil})
 	if err != nil {
 		return nil, err
 	}
-	arr = append(s.([]*Value), &v)
+	if m.kind == MapKind {
+		return m.asMap(), nil
+	}
+	return nil, fmt.Errorf("'%s' wants a map, got type '%s'", builtin, m.kind)
+}
+
+// resolveKey resolves the key argument 'k' of a map builtin
+func resolveKey(intptr *Interpreter) (Value, error) {
+	k, err := intptr.VariableResolver(Variable{Token{Lexeme: "k", Type: Identifier}, nil})
+	if err != nil {
+		return Value{}, err
+	}
+	return k, checkKey(k)
+}
+
+type Keys struct{}
+
+// evaluate is an array of the keys of map 'm' in the order they were added
+func (keys Keys) evaluate(intptr *Interpreter) (Value, error) {
+	m, err := resolveMap(intptr, "keys")
+	if err != nil {
+		return Value{}, err
+	}
+	vals := make([]Value, len(m.keys))
+	copy(vals, m.keys)
+	return arrayValue(vals), nil
+}
+
+type Values struct{}
+
+// evaluate is an array of the values of map 'm' in the same order as keys()
+func (values Values) evaluate(intptr *Interpreter) (Value, error) {
+	m, err := resolveMap(intptr, "values")
+	if err != nil {
+		return Value{}, err
+	}
+	vals := make([]Value, len(m.keys))
+	for i, key := range m.keys {
+		vals[i] = m.get(key)
+	}
+	return arrayValue(vals), nil
+}
+
+type Has struct{}
+
+func (has Has) evaluate(intptr *Interpreter) (Value, error) {
+	m, err := resolveMap(intptr, "has")
+	if err != nil {
+		return Value{}, err
+	}
+	k, err := resolveKey(intptr)
+	if err != nil {
+		return Value{}, err
+	}
+	return boolValue(m.has(k)), nil
+}
+
+type Delete struct{}
 
-	return arr, nil
+// evaluate removes key 'k' from map 'm' and is whether there was anything to remove
+func (d Delete) evaluate(intptr *Interpreter) (Value, error) {
+	m, err := resolveMap(intptr, "delete")
+	if err != nil {
+		return Value{}, err
+	}
+	k, err := resolveKey(intptr)
+	if err != nil {
+		return Value{}, err
+	}
+	return boolValue(m.delete(k)), nil
 }
 
 type Locals struct{}
 
 func (locals Locals) evaluate(intptr *Interpreter) (Value, error) {
-	if len(intptr.env.vars)-2 >= 0 {
-		prevStack := intptr.env.vars[len(intptr.env.vars)-2]
-		intptr.writeLog.Printf(prevStack.String())
+	if len(intptr.calls) > 0 {
+		caller := intptr.calls[len(intptr.calls)-1].caller
+		intptr.writeLog.Printf(caller.vars[len(caller.vars)-1].String())
 	}
-	return nil, nil
+	return Value{}, nil
+}
+
+// ErrorInit is the constructor of the builtin Error class. Its kind is the name of the class it's constructing,
+// so classes that inherit from Error are their own kind.
+type ErrorInit struct{}
+
+func (init ErrorInit) evaluate(intptr *Interpreter) (Value, error) {
+	this, err := intptr.VariableResolver(Variable{thisToken(0), nil})
+	if err != nil {
+		return Value{}, err
+	}
+	message, err := intptr.VariableResolver(Variable{Token{Lexeme: "message", Type: Identifier}, nil})
+	if err != nil {
+		return Value{}, err
+	}
+
+	instance := this.asInstance()
+	instance.initError(instance.parent.identifier.Lexeme, message, Value{})
+	return Value{}, nil
+}
+
+// initError sets the members every Error has. line is nil until it's thrown.
+func (this JlangClassInstance) initError(kind string, message Value, line Value) {
+	this.scope.varStore("kind", stringValue(kind))
+	this.scope.varStore("message", message)
+	this.scope.varStore("line", line)
+}
+
+// errorClass is the class of the errors a catch gets, which scripts can throw and inherit from too
+func errorClass() JlangClass {
+	constructor := makeBuiltinFunc("Error", []string{"this", "message"}, []Statement{
+		ExpressionStatement{ErrorInit{}},
+	})
+	(*constructor.args)[0].Type = This
+	constructor.arity = 1
+
+	class := JlangClass{identifier: Token{Lexeme: "Error", Type: Identifier}}
+	class.Stmt.constructor = &constructor
+	return class
 }
 
 func globals() []Statement {
 	globals := make([]Statement, 0)
 
-	globals = append(globals, VariableStatement{Token{"pi", Identifier, 0}, Literal{Token{"3.1415926535", Number, 0}}})
+	globals = append(globals, VariableStatement{Token{Lexeme: "pi", Type: Identifier}, Literal{Token{Lexeme: "3.1415926535", Type: Number, value: numberValue(3.1415926535)}}})
 
 	globals = append(globals, makeBuiltinFunc("len", []string{"v"}, []Statement{
-		ReturnStatement{Len{}, nil},
+		ReturnStatement{Len{}, Value{}},
 	}))
 	globals = append(globals, makeBuiltinFunc("time", nil, []Statement{
-		ReturnStatement{Time{}, nil},
+		ReturnStatement{Time{}, Value{}},
 	}))
 	globals = append(globals, makeBuiltinFunc("pow", []string{"x", "y"}, []Statement{
-		ReturnStatement{Pow{}, nil},
+		ReturnStatement{Pow{}, Value{}},
 	}))
 	globals = append(globals, makeBuiltinFunc("quit", nil, []Statement{
-		ReturnStatement{Quit{}, nil},
+		ReturnStatement{Quit{}, Value{}},
 	}))
 	globals = append(globals, makeBuiltinFunc("append", []string{"s", "v"}, []Statement{
-		ReturnStatement{AppendBuiltin{}, nil},
+		ReturnStatement{AppendBuiltin{}, Value{}},
+	}))
+	globals = append(globals, makeBuiltinFunc("map", []string{"s", "f"}, []Statement{
+		ReturnStatement{MapBuiltin{}, Value{}},
+	}))
+	globals = append(globals, makeBuiltinFunc("keys", []string{"m"}, []Statement{
+		ReturnStatement{Keys{}, Value{}},
+	}))
+	globals = append(globals, makeBuiltinFunc("values", []string{"m"}, []Statement{
+		ReturnStatement{Values{}, Value{}},
+	}))
+	globals = append(globals, makeBuiltinFunc("has", []string{"m", "k"}, []Statement{
+		ReturnStatement{Has{}, Value{}},
+	}))
+	globals = append(globals, makeBuiltinFunc("delete", []string{"m", "k"}, []Statement{
+		ReturnStatement{Delete{}, Value{}},
 	}))
 	globals = append(globals, makeBuiltinFunc("locals", nil, []Statement{
-		ReturnStatement{Locals{}, nil},
+		ReturnStatement{Locals{}, Value{}},
 	}))
+	globals = append(globals, errorClass())
 
 	return globals
 }
@@ -136,9 +289,9 @@ func makeBuiltinFunc(identifier string, args []string, block []Statement) Functi
 	if args != nil {
 		tokenArgs = make([]Token, len(args))
 		for i, arg := range args {
-			tokenArgs[i] = Token{arg, Identifier, 0}
+			tokenArgs[i] = Token{Lexeme: arg, Type: Identifier}
 		}
 	}
 
-	return FunctionDeclarationStatement{Token{identifier, Identifier, 0}, &tokenArgs, uint(len(args)), block}
+	return FunctionDeclarationStatement{Token{Lexeme: identifier, Type: Identifier}, &tokenArgs, uint(len(args)), block, ""}
 }
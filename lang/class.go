@@ -1,57 +1,115 @@
 package lang
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type JlangClass struct {
 	identifier  Token
-	constructor *FunctionInvocation
+	Doc         string // The comment directly above the declaration
+	constructor *JlangFunction
+	methods     map[string]JlangFunction
+	superclass  *JlangClass
+	closure     Environment // Where the class was declared, which is where its member variables are initialized
 	Stmt        struct {
+		superclass  *Variable
 		constructor *FunctionDeclarationStatement
 		varDecls    *[]VariableStatement
 		funcDecls   *[]FunctionDeclarationStatement
 	}
 }
 
-// evaluate is when a class is being _called_ i.e 'MyClass()'.
+func (class JlangClass) String() string {
+	return "class " + class.identifier.Lexeme
+}
+
+func (class JlangClass) signature() (Token, uint) {
+	if constructor := class.findConstructor(); constructor != nil {
+		return constructor.signature()
+	}
+	return class.identifier, 0
+}
+
+// findMethod looks up a method on the class and then up through its superclasses
+func (class *JlangClass) findMethod(identifier string) (JlangFunction, bool) {
+	for ; class != nil; class = class.superclass {
+		if method, found := class.methods[identifier]; found {
+			return method, true
+		}
+	}
+	return JlangFunction{}, false
+}
+
+// findConstructor is the class's own constructor, or the closest one it inherits
+func (class *JlangClass) findConstructor() *JlangFunction {
+	for ; class != nil; class = class.superclass {
+		if class.constructor != nil {
+			return class.constructor
+		}
+	}
+	return nil
+}
+
+// call is when a class is being _called_ i.e 'MyClass()'.
 // This is for creating a new JlangClassInstance using a JlangClass
-func (class JlangClass) evaluate(intptr *Interpreter) (Value, error) {
+func (class JlangClass) call(intptr *Interpreter, site Token, args []Value) (Value, error) {
 	scope := NewEnvironment(class.identifier.Lexeme)
 	instance := JlangClassInstance{&class, scope}
 
-	if varDecls := class.Stmt.varDecls; varDecls != nil {
-		for _, varDecl := range *varDecls {
-			err := instance.updateMember(intptr, varDecl)
-			if err != nil {
-				return nil, err
-			}
-		}
+	// Inherited members are declared first so the subclass can redeclare them
+	hierarchy := make([]*JlangClass, 0)
+	for c := &class; c != nil; c = c.superclass {
+		hierarchy = append([]*JlangClass{c}, hierarchy...)
 	}
-	if funcDecls := class.Stmt.funcDecls; funcDecls != nil {
-		for _, funcDecl := range *funcDecls {
-			instance.storeFunc(funcDecl)
+	for _, c := range hierarchy {
+		if err := instance.initMembers(intptr, c); err != nil {
+			return Value{}, err
 		}
 	}
-	if constructor := class.constructor; constructor != nil {
-		args := []Expression{instance}
-		if constructor.argExprs != nil {
-			args = append(args, *constructor.argExprs...)
-			constructor.arity = uint(len(*constructor.argExprs))
-		}
-		constructor.FillArgs(&args)
-		if _, err := constructor.evaluate(intptr); err != nil {
-			return nil, err
+	if constructor := class.findConstructor(); constructor != nil {
+		if _, err := constructor.bind(instance).call(intptr, site, args); err != nil {
+			return Value{}, err
 		}
 	}
 
-	return instance, nil
+	return instanceValue(instance), nil
 }
 
 // execute is when a JlangClass is being declared and has already been parsed.
 // This is when the class 'type' gets put into the interpreter's
-// environment to reference in the future i.e 'evaluate()'.
+// environment to reference in the future i.e 'call()'.
 func (class JlangClass) execute(intptr *Interpreter) error {
+	return class.declare(intptr, nil)
+}
+
+// declare sets up the class and stores it in the current scope. compiled is the bytecode of the constructor
+// and methods by name when the VM is declaring it, otherwise they're walked when they're called.
+func (class JlangClass) declare(intptr *Interpreter, compiled map[string]*chunk) error {
+	class.closure = intptr.env.capture()
+	closure := intptr.env.capture()
+	if class.Stmt.superclass != nil {
+		super, err := class.Stmt.superclass.evaluate(intptr)
+		if err != nil {
+			return err
+		}
+		if super.kind != ClassKind {
+			return InvalidSuperclass{class.identifier, class.Stmt.superclass.identifier}
+		}
+		class.superclass = super.asClass()
+
+		// Methods see the superclass as 'super'
+		closure.push(fmt.Sprintf("%s-super", class.identifier.Lexeme))
+		closure.varStore("super", super)
+	}
 	if class.Stmt.constructor != nil {
-		class.constructor = &FunctionInvocation{*class.Stmt.constructor, nil, 0}
+		class.constructor = &JlangFunction{*class.Stmt.constructor, closure, nil, compiled[class.identifier.Lexeme]}
+	}
+	class.methods = make(map[string]JlangFunction)
+	if funcDecls := class.Stmt.funcDecls; funcDecls != nil {
+		for _, funcDecl := range *funcDecls {
+			class.methods[funcDecl.Identifier.Lexeme] = JlangFunction{funcDecl, closure, nil, compiled[funcDecl.Identifier.Lexeme]}
+		}
 	}
 	intptr.env.classStore(class)
 	return nil
@@ -62,40 +120,51 @@ type JlangClassInstance struct {
 	scope  Environment
 }
 
-func (this JlangClassInstance) invoke(intptr *Interpreter, call FunctionCall) (Value, error) {
-	intptr.env.push(fmt.Sprintf("%s#%s", this.parent.identifier.Lexeme, call.identifier.Lexeme))
-	defer intptr.env.pop()
-
-	if funk, found := this.scope.funcResolve(call); found {
-		funk.arity = uint(len(*call.args))
-		args := append([]Expression{this}, *call.args...)
-		funk.FillArgs(&args)
-		return funk.evaluate(intptr)
+func (this JlangClassInstance) invoke(intptr *Interpreter, identifier Token, args []Value) (Value, error) {
+	if method, found := this.parent.findMethod(identifier.Lexeme); found {
+		return intptr.callValue(functionValue(method.bind(this)), identifier, args)
 	}
 
-	reason := fmt.Errorf("unresolved method '%s' for class of type '%s'.", call.identifier.Lexeme, this.parent.identifier.Lexeme)
-	if call.identifier.Lexeme == this.parent.identifier.Lexeme {
-		reason = fmt.Errorf("cannot call constructor of '%s' directly.", call.identifier.Lexeme)
+	reason := fmt.Errorf("unresolved method '%s' for class of type '%s'.", identifier.Lexeme, this.parent.identifier.Lexeme)
+	if identifier.Lexeme == this.parent.identifier.Lexeme {
+		reason = fmt.Errorf("cannot call constructor of '%s' directly.", identifier.Lexeme)
 	}
 
-	return nil, BadMethodInvocation{call.identifier, reason}
-}
-
-func (this JlangClassInstance) evaluate(intptr *Interpreter) (Value, error) {
-	return this, nil
+	return Value{}, BadMethodInvocation{identifier, reason}
 }
 
+// propertyAccess is a member variable, or if there isn't one, a method bound to the instance
 func (this JlangClassInstance) propertyAccess(identifier Token) (Value, error) {
-	var val Value
-	var err error
-
-	if val, err = this.scope.varResolve(Variable{identifier}); err != nil {
-		return nil, err
+	val, err := this.scope.varResolve(Variable{identifier, nil})
+	if err != nil {
+		if method, found := this.parent.findMethod(identifier.Lexeme); found {
+			return functionValue(method.bind(this)), nil
+		}
+		return Value{}, err
 	}
 
 	return val, nil
 }
 
+// initMembers declares the member variables of class on the instance. Their initializers see the scope
+// the class was declared in, not the scope the instance is being made in.
+func (this JlangClassInstance) initMembers(intptr *Interpreter, class *JlangClass) error {
+	if class.Stmt.varDecls == nil {
+		return nil
+	}
+	caller := intptr.env
+	intptr.env = class.closure
+	defer func() {
+		intptr.env = caller
+	}()
+	for _, varDecl := range *class.Stmt.varDecls {
+		if err := this.updateMember(intptr, varDecl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (this JlangClassInstance) updateMember(intptr *Interpreter, vari VariableStatement) error {
 	var val Value
 	var err error
@@ -105,14 +174,42 @@ func (this JlangClassInstance) updateMember(intptr *Interpreter, vari VariableSt
 		if err != nil {
 			return err
 		}
-	} else {
-		val = nil
 	}
-	this.scope.varStore(vari.Identifier.Lexeme, &val)
+	this.scope.varStore(vari.Identifier.Lexeme, val)
 	return nil
 }
 
-func (this *JlangClassInstance) storeFunc(fun FunctionDeclarationStatement) {
-	funInv := FunctionInvocation{fun, nil, 0}
-	this.scope.funcStore(funInv)
+// member is the member variable of the instance named name, if it has one
+func (this JlangClassInstance) member(name string) (Value, bool) {
+	return this.scope.vars[0].store.query(name)
+}
+
+// same is whether both are the same instance, not just instances with the same members
+func (this JlangClassInstance) same(other JlangClassInstance) bool {
+	return this.scope.vars[0].store == other.scope.vars[0].store
+}
+
+// String is the instance's class and its members i.e 'Rect{x: 1, y: 2}'
+func (this JlangClassInstance) String() string {
+	return this.format(nil)
+}
+
+// format is String for an instance inside the containers in seen, the same as Value.format. An instance
+// that's one of its own members is shown as 'Node{...}' when it comes up again.
+func (this JlangClassInstance) format(seen map[interface{}]bool) string {
+	members := this.scope.vars[0].store
+	if seen[members] {
+		return this.parent.identifier.Lexeme + "{...}"
+	}
+	if seen == nil {
+		seen = make(map[interface{}]bool)
+	}
+	seen[members] = true
+	defer delete(seen, members)
+
+	fields := make([]string, len(members.names))
+	for slot, name := range members.names {
+		fields[slot] = fmt.Sprintf("%s: %s", name, members.values[slot].format(seen))
+	}
+	return fmt.Sprintf("%s{%s}", this.parent.identifier.Lexeme, strings.Join(fields, ", "))
 }
@@ -0,0 +1,623 @@
+package lang
+
+import "fmt"
+
+// opcode is a VM instruction. Operands follow it in the code as big endian uint16s.
+type opcode byte
+
+const (
+	opConstant         opcode = iota // constant: push constants[constant]
+	opPop                            // pop the top of the stack
+	opGetLocal                       // depth slot node: push the variable in slot of the block depth out
+	opSetLocal                       // depth slot node: pop into the variable in slot of the block depth out
+	opGetGlobal                      // node callee: push the global the Variable at node is
+	opSetGlobal                      // node: pop into the global the AssignmentStatement at node assigns
+	opGetName                        // node callee: push a Variable the Resolver didn't resolve, looked up by name
+	opSetName                        // node: pop into a variable the Resolver didn't resolve, looked up by name
+	opDeclare                        // constant: pop into a new variable named constants[constant] in the innermost block
+	opPushBlock                      // constant: push a block named constants[constant]
+	opPopBlock                       // count: pop count blocks
+	opJump                           // target: continue at target
+	opJumpIfFalse                    // target: pop, and continue at target if it isn't true
+	opJumpIfTrueOrPop                // target: continue at target if the top is true, otherwise pop it
+	opJumpIfFalseOrPop               // target: continue at target if the top isn't true, otherwise pop it
+	opAdd                            // node: pop the right and left operands and push the Binary at node applied to them
+	opSubtract                       // node
+	opMultiply                       // node
+	opDivide                         // node
+	opLess                           // node
+	opGreater                        // node
+	opBinary                         // node: any other Binary
+	opNegate                         // node: pop the operand and push the Unary at node applied to it
+	opIncrement                      // node
+	opDecrement                      // node
+	opUnary                          // node: any other Unary
+	opCall                           // count node: pop count arguments and the callee, push the result of calling it from the site at node
+	opCheckInvoke                    // node: make sure the top can have the MethodInvocation at node invoked on it
+	opInvoke                         // count node: pop count arguments and an instance, push the result of invoking the method on it
+	opGetProperty                    // node: pop an object and push the PropertyAccess at node on it
+	opCheckProperty                  // node: make sure the top can have the PropertyAssignmentStatement at node assign to it
+	opSetProperty                    // node: pop a value and an instance, assigning the value to the member
+	opArray                          // count: pop count elements and push an array of them
+	opMap                            // push an empty map
+	opCheckKey                       // node: make sure the top can be a map key, the Expression at node is where it's from
+	opMapSet                         // pop a value and a key, and set them in the map below them
+	opIndex                          // node: pop an index and a container and push the ArrayAccess at node of them
+	opCheckIndex                     // node: make sure the top two are a container and an index the ArrayAccess at node can use
+	opSetIndex                       // pop a value, an index and a container, setting the value at the index
+	opConcat                         // count: pop count values and push them stringified and joined
+	opClosure                        // node: push the function at node closing over the current scope
+	opClass                          // node: declare the class at node
+	opPrint                          // pop and print
+	opThrow                          // node: pop a value and throw it from the ThrowStatement at node
+	opTry                            // target: until opEndTry, an error continues at target with what it's caught as pushed
+	opFinally                        // target: until opEndTry, an error continues at target and is kept for opRethrow
+	opEndTry                         // stop the innermost opTry or opFinally catching errors
+	opRethrow                        // throw the error the last opFinally kept again
+	opReturn                         // pop and return it
+	opSuper                          // node: pop 'this' and the superclass and push the SuperAccess at node bound to them
+	opEval                           // node: push the Expression at node evaluated by walking it
+	opExec                           // node: execute the Statement at node by walking it
+)
+
+// chunk is the compiled code of a Program or a function body
+type chunk struct {
+	code      []byte
+	constants []Value
+	nodes     []interface{} // The AST nodes and Tokens instructions need for their errors, or to fall back on walking
+	globals   []int         // The slot in the global block each node's global was found at, or -1 until it's looked up
+
+	// A function body's own block has a slot for each of names, its parameters and then what it declares in order.
+	// Its blocks are reused by later calls unless it captures them in a closure or class.
+	names    []string
+	captures bool
+	frames   []*varMap
+}
+
+// compiledClass is a JlangClass with its constructor and methods compiled, by name
+type compiledClass struct {
+	class   JlangClass
+	methods map[string]*chunk
+}
+
+// compiler compiles one chunk. It keeps track of the blocks the chunk pushes so 'break' and 'continue' can pop
+// the ones they jump out of, the same as the tree walker unwinding out of them. It keeps track of the tries
+// they jump out of too, to run their finally blocks on the way.
+type compiler struct {
+	chunk     *chunk
+	constants map[Value]int
+	blocks    int
+	loops     []*loopJumps
+	tries     []tryRegion
+	err       error
+}
+
+// loopJumps is the 'break' and 'continue' jumps of a loop that are patched once the loop is compiled
+type loopJumps struct {
+	blocks    int // How many blocks were pushed when the loop started
+	tries     int // How many tries the loop is in
+	breaks    []int
+	continues []int
+}
+
+// tryRegion is code that has a handler catching its errors, which is the body of a try, or its catch when
+// there's a finally
+type tryRegion struct {
+	blocks  int // How many blocks were pushed where the try is
+	loops   int // How many loops the try is in
+	finally *[]Statement
+}
+
+// compile compiles a Program to bytecode for the VM. The Program is expected to have been resolved already.
+func compile(program *Program) (*chunk, error) {
+	c := newCompiler()
+	for _, stmt := range program.Statements {
+		c.statement(stmt)
+	}
+	return c.finish()
+}
+
+// compileFunction compiles the body of a function, which returns nil if it runs off its end
+func compileFunction(decl FunctionDeclarationStatement) (*chunk, error) {
+	c := newCompiler()
+	if decl.args != nil {
+		for _, param := range *decl.args {
+			c.chunk.names = append(c.chunk.names, param.Lexeme)
+		}
+	}
+	for _, stmt := range decl.block {
+		c.statement(stmt)
+	}
+	return c.finish()
+}
+
+func newCompiler() *compiler {
+	return &compiler{chunk: &chunk{}, constants: make(map[Value]int)}
+}
+
+func (c *compiler) finish() (*chunk, error) {
+	c.emit(opConstant, c.constant(Value{}))
+	c.emit(opReturn)
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.chunk.names = c.chunk.names[:len(c.chunk.names):len(c.chunk.names)]
+	c.chunk.globals = make([]int, len(c.chunk.nodes))
+	for i := range c.chunk.globals {
+		c.chunk.globals[i] = -1
+	}
+	return c.chunk, nil
+}
+
+func (c *compiler) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case VariableStatement:
+		if s.Expr != nil {
+			c.expression(s.Expr)
+		} else {
+			c.emit(opConstant, c.constant(Value{}))
+		}
+		c.declare(s.Identifier.Lexeme)
+	case AssignmentStatement:
+		c.expression(s.Expr)
+		switch local := s.local; {
+		case local != nil && local.resolved && local.depth >= 0:
+			c.emit(opSetLocal, local.depth, local.slot, c.node(s))
+		case local != nil && local.resolved:
+			c.emit(opSetGlobal, c.node(s))
+		default:
+			c.emit(opSetName, c.node(s))
+		}
+	case PropertyAssignmentStatement:
+		c.expression(s.get.Expr)
+		c.emit(opCheckProperty, c.node(s))
+		c.expression(s.value)
+		c.emit(opSetProperty, c.node(s))
+	case IndexAssignmentStatement:
+		c.expression(s.get.Expr)
+		c.expression(s.get.index)
+		c.emit(opCheckIndex, c.node(s.get))
+		c.expression(s.value)
+		c.emit(opSetIndex)
+	case IfStatement:
+		c.expression(s.Expr)
+		otherwise := c.jump(opJumpIfFalse)
+		c.block("if", s.block)
+		if s.elseBlock == nil {
+			c.patch(otherwise)
+			break
+		}
+		end := c.jump(opJump)
+		c.patch(otherwise)
+		c.block("if", *s.elseBlock)
+		c.patch(end)
+	case WhileStatement:
+		start := len(c.chunk.code)
+		c.expression(s.test)
+		exit := c.jump(opJumpIfFalse)
+		c.loop(s.block)
+		c.emit(opJump, start)
+		c.endLoop(exit)
+	case ForStatement:
+		c.pushBlock("for-stmt")
+		if s.varStmt != nil {
+			c.statement(*s.varStmt)
+		}
+		start := len(c.chunk.code)
+		c.expression(s.test)
+		exit := c.jump(opJumpIfFalse)
+		c.loop(s.block)
+		c.statement(s.assign)
+		c.emit(opJump, start)
+		c.endLoop(exit)
+		c.popBlocks(1)
+	case BreakStatement:
+		loop := c.loops[len(c.loops)-1]
+		c.leave(loop.tries)
+		c.unwind(loop.blocks)
+		loop.breaks = append(loop.breaks, c.jump(opJump))
+	case ContinueStatement:
+		loop := c.loops[len(c.loops)-1]
+		c.leave(loop.tries)
+		c.unwind(loop.blocks)
+		loop.continues = append(loop.continues, c.jump(opJump))
+	case ThrowStatement:
+		c.expression(s.Expression)
+		c.emit(opThrow, c.node(s))
+	case TryStatement:
+		c.try(s)
+	case ExpressionStatement:
+		c.expression(s.Expression)
+		c.emit(opPop)
+	case ReturnStatement:
+		c.expression(s.Expression)
+		c.leave(0)
+		c.emit(opReturn)
+	case PrintStatement:
+		c.expression(s.Expression)
+		c.emit(opPrint)
+	case FunctionDeclarationStatement:
+		c.emit(opClosure, c.function(s))
+		c.declare(s.Identifier.Lexeme)
+	case JlangClass:
+		c.emit(opClass, c.class(s))
+		c.named(s.identifier.Lexeme)
+		c.chunk.captures = true
+	default:
+		c.emit(opExec, c.node(stmt))
+		c.chunk.captures = true
+	}
+}
+
+// block compiles the body of an if, try or finally, which only gets a block of its own if it declares something
+func (c *compiler) block(id string, stmts []Statement) {
+	if !declares(stmts) {
+		for _, stmt := range stmts {
+			c.statement(stmt)
+		}
+		return
+	}
+	c.pushBlock(id)
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+	c.popBlocks(1)
+}
+
+// loop compiles the body of a loop. The 'continue's in it jump to right after it.
+func (c *compiler) loop(stmts []Statement) {
+	loop := &loopJumps{blocks: c.blocks, tries: len(c.tries)}
+	c.loops = append(c.loops, loop)
+	c.block("loop", stmts)
+	for _, jump := range loop.continues {
+		c.patch(jump)
+	}
+}
+
+// endLoop patches the jumps out of the innermost loop to here, exit being the jump taken when its test fails
+func (c *compiler) endLoop(exit int) {
+	loop := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+	c.patch(exit)
+	for _, jump := range loop.breaks {
+		c.patch(jump)
+	}
+}
+
+// try compiles a try statement. Its finally is compiled after the try and after the catch, and again for
+// when either of them throws, which rethrows the error once the finally is done.
+func (c *compiler) try(stmt TryStatement) {
+	handler := opTry
+	if stmt.catchBlock == nil {
+		handler = opFinally
+	}
+	caught := c.beginTry(handler, stmt.finallyBlock, c.blocks)
+	c.block("try", stmt.block)
+	c.endTry()
+	c.finally(stmt.finallyBlock)
+	ends := []int{c.jump(opJump)}
+	c.patch(caught)
+
+	if stmt.catchBlock != nil {
+		c.pushBlock("catch")
+		c.declare(stmt.catchParam.Lexeme)
+		rethrow := 0
+		if stmt.finallyBlock != nil {
+			// The handler is inside the catch's block, but the finally runs in the block the try is in
+			rethrow = c.beginTry(opFinally, stmt.finallyBlock, c.blocks-1)
+		}
+		for _, stmt := range *stmt.catchBlock {
+			c.statement(stmt)
+		}
+		if stmt.finallyBlock == nil {
+			c.popBlocks(1)
+			c.patch(ends[0])
+			return
+		}
+		c.endTry()
+		c.popBlocks(1)
+		c.finally(stmt.finallyBlock)
+		ends = append(ends, c.jump(opJump))
+		c.patch(rethrow)
+		c.emit(opPopBlock, 1)
+	}
+	c.finally(stmt.finallyBlock)
+	c.emit(opRethrow)
+	for _, end := range ends {
+		c.patch(end)
+	}
+}
+
+// beginTry emits a handler for the code up to the next endTry, and is where its jump to the handling code is.
+// blocks is how many blocks are pushed where the try is.
+func (c *compiler) beginTry(handler opcode, finally *[]Statement, blocks int) int {
+	c.tries = append(c.tries, tryRegion{blocks, len(c.loops), finally})
+	return c.jump(handler)
+}
+
+func (c *compiler) endTry() {
+	c.emit(opEndTry)
+	c.tries = c.tries[:len(c.tries)-1]
+}
+
+func (c *compiler) finally(finally *[]Statement) {
+	if finally != nil {
+		c.block("finally", *finally)
+	}
+}
+
+// leave drops the handlers of the tries since there were tries of them, running their finally blocks, for a
+// 'return', 'break' or 'continue' jumping out of them. Each finally is compiled as if it were where its try is.
+func (c *compiler) leave(tries int) {
+	for i := len(c.tries) - 1; i >= tries; i-- {
+		try := c.tries[i]
+		c.unwind(try.blocks)
+		c.emit(opEndTry)
+		if try.finally == nil {
+			continue
+		}
+
+		outer, loops, blocks := c.tries, c.loops, c.blocks
+		c.tries = append([]tryRegion{}, c.tries[:i]...)
+		c.loops = append([]*loopJumps{}, c.loops[:try.loops]...)
+		c.blocks = try.blocks
+		c.finally(try.finally)
+		c.tries, c.loops, c.blocks = outer, loops, blocks
+	}
+}
+
+// declare pops into a new variable in the innermost block
+func (c *compiler) declare(name string) {
+	c.emit(opDeclare, c.constant(stringValue(name)))
+	c.named(name)
+}
+
+// named is a variable being declared, which gets the next slot of the chunk's own block if it's declared in it
+func (c *compiler) named(name string) {
+	if c.blocks > 0 {
+		return
+	}
+	for _, declared := range c.chunk.names {
+		if declared == name {
+			return
+		}
+	}
+	c.chunk.names = append(c.chunk.names, name)
+}
+
+func (c *compiler) pushBlock(id string) {
+	c.emit(opPushBlock, c.constant(stringValue(id)))
+	c.blocks++
+}
+
+func (c *compiler) popBlocks(count int) {
+	c.emit(opPopBlock, count)
+	c.blocks -= count
+}
+
+// unwind pops the blocks pushed since there were blocks of them, without forgetting them at compile time since
+// the code after a 'break' or 'continue' is still in them
+func (c *compiler) unwind(blocks int) {
+	if c.blocks > blocks {
+		c.emit(opPopBlock, c.blocks-blocks)
+	}
+}
+
+func (c *compiler) expression(expr Expression) {
+	switch e := expr.(type) {
+	case Literal:
+		val, err := e.evaluate(nil)
+		if err != nil {
+			c.eval(e)
+			break
+		}
+		c.emit(opConstant, c.constant(val))
+	case Grouping:
+		c.expression(e.Expr)
+	case Unary:
+		if e.Expr == nil {
+			c.eval(e)
+			break
+		}
+		c.expression(e.Expr)
+		switch e.Op.Type {
+		case Minus:
+			c.emit(opNegate, c.node(e))
+		case PlusPlus:
+			c.emit(opIncrement, c.node(e))
+		case MinusMinus:
+			c.emit(opDecrement, c.node(e))
+		default:
+			c.emit(opUnary, c.node(e))
+		}
+	case Binary:
+		c.expression(e.Left)
+		c.expression(e.Right)
+		switch e.Op.Type {
+		case Plus:
+			c.emit(opAdd, c.node(e))
+		case Minus:
+			c.emit(opSubtract, c.node(e))
+		case Star:
+			c.emit(opMultiply, c.node(e))
+		case Slash:
+			c.emit(opDivide, c.node(e))
+		case Less:
+			c.emit(opLess, c.node(e))
+		case Greater:
+			c.emit(opGreater, c.node(e))
+		default:
+			c.emit(opBinary, c.node(e))
+		}
+	case Logical:
+		switch e.Op.Type {
+		case Or:
+			c.expression(e.Left)
+			end := c.jump(opJumpIfTrueOrPop)
+			c.expression(e.Right)
+			c.patch(end)
+		case And:
+			c.expression(e.Left)
+			end := c.jump(opJumpIfFalseOrPop)
+			c.expression(e.Right)
+			c.patch(end)
+		default:
+			c.eval(e)
+		}
+	case Variable:
+		c.variable(e, false)
+	case Call:
+		if callee, ok := e.callee.(Variable); ok {
+			c.variable(callee, true)
+		} else {
+			c.expression(e.callee)
+		}
+		c.emit(opCall, c.arguments(e.args), c.node(spanOf(e.callee)))
+	case Lambda:
+		c.emit(opClosure, c.function(e.decl))
+	case MethodInvocation:
+		c.expression(e.this)
+		c.emit(opCheckInvoke, c.node(e))
+		c.emit(opInvoke, c.arguments(e.argExprs), c.node(e))
+	case PropertyAccess:
+		c.expression(e.Expr)
+		c.emit(opGetProperty, c.node(e))
+	case ArrayLiteral:
+		for _, element := range e.elements {
+			c.expression(element)
+		}
+		c.emit(opArray, len(e.elements))
+	case MapLiteral:
+		c.emit(opMap)
+		for i, key := range e.keys {
+			c.expression(key)
+			c.emit(opCheckKey, c.node(key))
+			c.expression(e.values[i])
+			c.emit(opMapSet)
+		}
+	case ArrayAccess:
+		c.expression(e.Expr)
+		c.expression(e.index)
+		c.emit(opIndex, c.node(e))
+	case SuperAccess:
+		// 'super' and 'this' are only ever looked up by name
+		c.variable(Variable{e.keyword, nil}, false)
+		c.variable(Variable{thisToken(e.keyword.Line), nil}, false)
+		c.emit(opSuper, c.node(e))
+	case Interpolation:
+		for _, part := range e.parts {
+			c.expression(part)
+		}
+		c.emit(opConcat, len(e.parts))
+	default:
+		c.eval(expr)
+	}
+}
+
+// eval falls back on walking an expression, which is only the builtins and expressions that are always an error.
+// None of them close over the chunk's blocks.
+func (c *compiler) eval(expr Expression) {
+	c.emit(opEval, c.node(expr))
+}
+
+// variable compiles getting a Variable, callee is whether it's being called since an unknown callee is a BadCall
+func (c *compiler) variable(variable Variable, callee bool) {
+	flag := 0
+	if callee {
+		flag = 1
+	}
+	switch local := variable.local; {
+	case local != nil && local.resolved && local.depth >= 0:
+		c.emit(opGetLocal, local.depth, local.slot, c.node(variable))
+	case local != nil && local.resolved:
+		c.emit(opGetGlobal, c.node(variable), flag)
+	default:
+		c.emit(opGetName, c.node(variable), flag)
+	}
+}
+
+// arguments compiles each argument in order and is how many there are
+func (c *compiler) arguments(args *[]Expression) int {
+	if args == nil {
+		return 0
+	}
+	for _, arg := range *args {
+		c.expression(arg)
+	}
+	return len(*args)
+}
+
+// function compiles a function declaration to a JlangFunction that opClosure gives a closure
+func (c *compiler) function(decl FunctionDeclarationStatement) int {
+	c.chunk.captures = true
+	code, err := compileFunction(decl)
+	if err != nil {
+		c.fail(err)
+	}
+	return c.node(JlangFunction{decl, Environment{}, nil, code})
+}
+
+func (c *compiler) class(class JlangClass) int {
+	compiled := compiledClass{class, make(map[string]*chunk)}
+	decls := make([]FunctionDeclarationStatement, 0)
+	if class.Stmt.constructor != nil {
+		decls = append(decls, *class.Stmt.constructor)
+	}
+	if class.Stmt.funcDecls != nil {
+		decls = append(decls, *class.Stmt.funcDecls...)
+	}
+	for _, decl := range decls {
+		code, err := compileFunction(decl)
+		if err != nil {
+			c.fail(err)
+		}
+		compiled.methods[decl.Identifier.Lexeme] = code
+	}
+	return c.node(compiled)
+}
+
+// constant is the index of val in the constant pool, adding it if it isn't in it yet
+func (c *compiler) constant(val Value) int {
+	if i, found := c.constants[val]; found {
+		return i
+	}
+	c.chunk.constants = append(c.chunk.constants, val)
+	c.constants[val] = len(c.chunk.constants) - 1
+	return len(c.chunk.constants) - 1
+}
+
+func (c *compiler) node(node interface{}) int {
+	c.chunk.nodes = append(c.chunk.nodes, node)
+	return len(c.chunk.nodes) - 1
+}
+
+func (c *compiler) emit(op opcode, operands ...int) {
+	c.chunk.code = append(c.chunk.code, byte(op))
+	for _, operand := range operands {
+		if operand < 0 || operand > 0xFFFF {
+			c.fail(InternalError{11, fmt.Sprintf("operand %d of a compiled instruction is out of range", operand)})
+		}
+		c.chunk.code = append(c.chunk.code, byte(operand>>8), byte(operand))
+	}
+}
+
+// jump emits a jump that's patched to its target later, and is where its operand is
+func (c *compiler) jump(op opcode) int {
+	c.emit(op, 0)
+	return len(c.chunk.code) - 2
+}
+
+// patch points the jump whose operand is at operand to the end of the code
+func (c *compiler) patch(operand int) {
+	target := len(c.chunk.code)
+	if target > 0xFFFF {
+		c.fail(InternalError{11, "compiled code is too long to jump through"})
+	}
+	c.chunk.code[operand], c.chunk.code[operand+1] = byte(target>>8), byte(target)
+}
+
+func (c *compiler) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
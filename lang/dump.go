@@ -0,0 +1,197 @@
+package lang
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// DumpJSON is the AST of a node as indented JSON. Every node is an object with its "kind", the "span" of
+// source it was parsed from when it's an expression, and one key per field. Tokens are objects with their
+// "type", "lexeme" and source position, and missing parts of a node are null.
+func DumpJSON(node Node) (string, error) {
+	out, err := json.MarshalIndent(jsonNode(node), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// DumpSexp is the AST of a node as an S-expression i.e '(Binary 1 + (Grouping (Binary 2 * x)))'.
+// Lists of nodes are in [brackets] and missing parts of a node are '_'. Lines are broken to keep them under 80 columns.
+func DumpSexp(node Node) string {
+	return sexpNode(node).render(0)
+}
+
+// jsonObject is a JSON object that keeps its keys in order, unlike a map
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+func (obj jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, member := range obj {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func jsonNode(node Node) interface{} {
+	if node == nil {
+		return nil
+	}
+	obj := jsonObject{{"kind", Kind(node)}}
+	// Statements that embed their Expression look like one, but only expressions have a span
+	if _, stmt := node.(Statement); !stmt {
+		if expr, ok := node.(Expression); ok {
+			obj = append(obj, jsonMember{"span", jsonPosition(spanOf(expr))})
+		}
+	}
+	for _, field := range Fields(node) {
+		obj = append(obj, jsonMember{field.Name, jsonValue(field.Value)})
+	}
+	return obj
+}
+
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case Token:
+		return append(jsonObject{{"type", v.TypeString()}, {"lexeme", v.Lexeme}}, jsonPosition(v)...)
+	case []Token:
+		tokens := make([]interface{}, len(v))
+		for i, token := range v {
+			tokens[i] = jsonValue(token)
+		}
+		return tokens
+	case []Node:
+		nodes := make([]interface{}, len(v))
+		for i, node := range v {
+			nodes[i] = jsonNode(node)
+		}
+		return nodes
+	}
+	return jsonNode(value)
+}
+
+func jsonPosition(token Token) jsonObject {
+	return jsonObject{{"line", token.Line}, {"column", token.Column}, {"start", token.Start}, {"end", token.End}}
+}
+
+// sexp is an atom when it has no open, otherwise a list of items between open and close
+type sexp struct {
+	atom        string
+	open, close string
+	items       []sexp
+}
+
+func sexpNode(node Node) sexp {
+	switch n := node.(type) {
+	case nil:
+		return sexp{atom: "_"}
+	case Literal:
+		if n.is(String) {
+			return sexp{atom: strconv.Quote(n.Lexeme)}
+		}
+		return sexp{atom: n.Lexeme}
+	case Variable:
+		return sexp{atom: n.identifier.Lexeme}
+	}
+
+	list := sexp{open: "(", close: ")", items: []sexp{{atom: Kind(node)}}}
+	for _, field := range Fields(node) {
+		if doc, ok := field.Value.(string); ok {
+			if doc != "" {
+				list.items = append(list.items, sexp{atom: strconv.Quote(doc)})
+			}
+			continue
+		}
+		list.items = append(list.items, sexpValue(field.Value))
+	}
+	return list
+}
+
+func sexpValue(value interface{}) sexp {
+	switch v := value.(type) {
+	case Token:
+		return sexp{atom: v.Lexeme}
+	case []Token:
+		list := sexp{open: "[", close: "]"}
+		for _, token := range v {
+			list.items = append(list.items, sexpValue(token))
+		}
+		return list
+	case []Node:
+		list := sexp{open: "[", close: "]"}
+		for _, node := range v {
+			list.items = append(list.items, sexpNode(node))
+		}
+		return list
+	}
+	return sexpNode(value)
+}
+
+func (s sexp) flat() string {
+	if s.open == "" {
+		return s.atom
+	}
+	items := make([]string, len(s.items))
+	for i, item := range s.items {
+		items[i] = item.flat()
+	}
+	return s.open + strings.Join(items, " ") + s.close
+}
+
+// render writes s on one line if it fits, otherwise the leading atoms stay on the first line and
+// every other item gets its own line
+func (s sexp) render(indent int) string {
+	flat := s.flat()
+	if s.open == "" || indent+len(flat) <= 80 {
+		return flat
+	}
+
+	b := strings.Builder{}
+	b.WriteString(s.open)
+	i := 0
+	for ; i < len(s.items) && s.items[i].open == ""; i++ {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s.items[i].atom)
+	}
+	// Lists line up under their first item, nodes are indented under their kind
+	inner := indent + 2
+	if s.open == "[" {
+		inner = indent + 1
+	}
+	for j := i; j < len(s.items); j++ {
+		if j > 0 {
+			b.WriteString("\n" + strings.Repeat(" ", inner))
+		}
+		b.WriteString(s.items[j].render(inner))
+	}
+	b.WriteString(s.close)
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package lang
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDumpSexp(t *testing.T) {
+	program, err := parseSource(t, "var x = 1 + 2 * -y;\nif a or b and c { print(f(x)[0]); }\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "" +
+		"(Program\n" +
+		"  [(VariableStatement x (Binary 1 + (Binary 2 * (Unary - y))))\n" +
+		"   (IfStatement\n" +
+		"     (Logical a or (Logical b and c))\n" +
+		"     [(PrintStatement (Grouping (ArrayAccess (Call f [x]) 0)))]\n" +
+		"     _)])"
+	if dump := DumpSexp(program); dump != expected {
+		t.Errorf("dump did not match, got:\n%s", dump)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	program, err := parseSource(t, "print \"sum\" + 1;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump, err := DumpJSON(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tree struct {
+		Kind       string
+		Statements []struct {
+			Kind  string
+			Value struct {
+				Kind     string
+				Span     struct{ Line, Column, Start, End uint }
+				Operator struct{ Type, Lexeme string }
+				Right    struct {
+					Value struct{ Lexeme string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(dump), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Kind != "Program" || len(tree.Statements) != 1 || tree.Statements[0].Kind != "PrintStatement" {
+		t.Fatalf("expected a Program with a PrintStatement, got:\n%s", dump)
+	}
+	binary := tree.Statements[0].Value
+	if binary.Kind != "Binary" || binary.Operator.Type != "Plus" || binary.Right.Value.Lexeme != "1" {
+		t.Errorf("expected a Binary '+', got:\n%s", dump)
+	}
+	if binary.Span.Line != 1 || binary.Span.Column != 7 || binary.Span.Start != 6 || binary.Span.End != 15 {
+		t.Errorf("expected the span of '\"sum\" + 1', got %+v", binary.Span)
+	}
+}
@@ -1,142 +1,166 @@
 package lang
 
-import (
-	"fmt"
-	"reflect"
-)
-
 type Environment struct {
-	vars  []Block
-	funcs []Block
+	vars []Block
 }
 
 type Block struct {
 	id    string
-	store interface {
-		query(id string) (interface{}, bool)
-		fmt.Stringer
-	}
+	store *varMap
 }
 
-type varMap map[string]*Value
-type funcMap map[string]FunctionInvocation
+// varMap is the variables declared in a block. Each one gets the next slot in the block as it's declared,
+// which is the same index the Resolver gives the Variables that refer to it.
+type varMap struct {
+	names  []string // Can run ahead of values in the block of a compiled call, whose names are known before they're declared
+	values []Value
+	slots  map[string]int // Only made once the block is big enough that looking names up one by one is slow
+}
 
-func (vars varMap) query(id string) (interface{}, bool) {
-	val, found := vars[id]
-	if val == nil {
-		return nil, found
-	}
+func newVarMap() *varMap {
+	return &varMap{}
+}
 
-	return *val, found
+// newFrame is the block of a call to compiled code, which has room for every slot it'll declare.
+// names is shared by every call so it's copied before it's changed, which is only if they're declared out of order.
+func newFrame(names []string) *varMap {
+	return &varMap{names: names, values: make([]Value, 0, len(names))}
 }
 
-func (funs funcMap) query(id string) (interface{}, bool) {
-	fun, found := funs[id]
-	return fun, found
+// declared is the names of the slots that have been declared
+func (vars *varMap) declared() []string {
+	return vars.names[:len(vars.values)]
 }
 
-func (env Environment) varResolve(variable Variable) (Value, error) {
-	for i := len(env.vars) - 1; i >= 0; i-- {
-		val, found := env.vars[i].store.query(variable.identifier.Lexeme)
-		if !found {
-			continue
+// slot is the slot id was declared in
+func (vars *varMap) slot(id string) (int, bool) {
+	if vars.slots == nil && len(vars.values) > 8 {
+		vars.slots = make(map[string]int, len(vars.values))
+		for slot, name := range vars.declared() {
+			vars.slots[name] = slot
 		}
-		if val == nil {
-			return nil, nil
+	}
+	if vars.slots != nil {
+		slot, found := vars.slots[id]
+		return slot, found
+	}
+	for slot, name := range vars.declared() {
+		if name == id {
+			return slot, true
 		}
-		return val, nil
 	}
-	return nil, UnknownIdentifier{variable.identifier}
+	return 0, false
 }
 
-func (env Environment) funcResolve(call FunctionCall) (FunctionInvocation, bool) {
-	var fun FunctionInvocation
-	for i := len(env.funcs) - 1; i >= 0; i-- {
-		funIn, found := env.funcs[i].store.query(call.identifier.Lexeme)
-		if found {
-			fun = funIn.(FunctionInvocation)
-			return fun, found
-		}
+func (vars *varMap) query(id string) (Value, bool) {
+	slot, found := vars.slot(id)
+	if !found {
+		return Value{}, false
 	}
-
-	return fun, false
+	return vars.values[slot], true
 }
 
-func (env Environment) arrayResolve(arr ArrayAccess, index int) (Value, error) {
-	var valArr []*Value
-	varBlock, err := env.varResolve(Variable{arr.identifier})
-	if err != nil {
-		return nil, err
+// declare stores the variable in its own slot, or the slot it already has if it's being redeclared
+func (vars *varMap) declare(id string, val Value) {
+	if slot, found := vars.slot(id); found {
+		vars.values[slot] = val
+		return
+	}
+	slot := len(vars.values)
+	switch {
+	case slot == len(vars.names):
+		vars.names = append(vars.names, id)
+	case vars.names[slot] != id:
+		vars.names = append(vars.names[:slot:slot], id)
 	}
-	if kind := reflect.TypeOf(varBlock).Kind(); kind != reflect.Slice {
-		return nil, InternalError{50, fmt.Sprintf("wanted array type for access statement got '%s'.", kind)}
+	if vars.slots != nil {
+		vars.slots[id] = slot
 	}
+	vars.values = append(vars.values, val)
+}
 
-	valArr = varBlock.([]*Value)
+// reset empties the block of a compiled call so another call of the code with names can use it
+func (vars *varMap) reset(names []string) {
+	for slot := range vars.values {
+		vars.values[slot] = Value{}
+	}
+	vars.names, vars.values, vars.slots = names, vars.values[:0], nil
+}
 
-	if arrLen := len(valArr) - 1; arrLen < index {
-		return nil, OutOfBounds{arr.identifier.Lexeme, index, arrLen}
+// block is the block a resolved variable is in, or nil if the variable isn't a resolved local
+func (env Environment) block(local *local) *varMap {
+	if local == nil || !local.resolved || local.depth < 0 {
+		return nil
 	}
-	if valArr[index] != nil {
-		return *valArr[index], nil
-	} else {
-		return nil, nil
+	if i := len(env.vars) - 1 - local.depth; i >= 0 && local.slot < len(env.vars[i].store.values) {
+		return env.vars[i].store
 	}
+	return nil
 }
 
-func (env Environment) classResolve(identifier Token) (JlangClass, error) {
-	var class JlangClass
-
-	val, err := env.varResolve(Variable{identifier})
-	if err != nil {
-		return JlangClass{}, err
+func (env Environment) varResolve(variable Variable) (Value, error) {
+	if vars := env.block(variable.local); vars != nil {
+		return vars.values[variable.local.slot], nil
 	}
-	if typeStr := reflect.TypeOf(val).String(); typeStr != "lang.JlangClass" {
-		return class, fmt.Errorf("'%s' is not a Class type - is %s", identifier.Lexeme, typeStr)
+	for i := env.lookupFrom(variable.local); i >= 0; i-- {
+		if val, found := env.vars[i].store.query(variable.identifier.Lexeme); found {
+			return val, nil
+		}
 	}
-	class = val.(JlangClass)
-
-	return class, nil
+	return Value{}, UnknownIdentifier{variable.identifier}
 }
 
-func (env Environment) varStore(identifier string, val *Value) {
-	env.vars[len(env.vars)-1].store.(varMap)[identifier] = val
+func (env Environment) varStore(identifier string, val Value) {
+	env.vars[len(env.vars)-1].store.declare(identifier, val)
 }
 
-func (env Environment) funcStore(fun FunctionInvocation) {
-	env.funcs[len(env.funcs)-1].store.(funcMap)[fun.stmt.Identifier.Lexeme] = fun
+// varAssign updates the variable in the innermost block it was declared in
+func (env Environment) varAssign(identifier Token, local *local, val Value) error {
+	if vars := env.block(local); vars != nil {
+		vars.values[local.slot] = val
+		return nil
+	}
+	for i := env.lookupFrom(local); i >= 0; i-- {
+		vars := env.vars[i].store
+		if slot, found := vars.slot(identifier.Lexeme); found {
+			vars.values[slot] = val
+			return nil
+		}
+	}
+	return UnknownIdentifier{identifier}
 }
 
-func (env Environment) classStore(class JlangClass) {
-	x := magic(class)
-	env.vars[len(env.vars)-1].store.(varMap)[class.identifier.Lexeme] = &x
+// lookupFrom is the block to start looking a variable up by name from. Variables the Resolver found to be
+// globals are only looked for in the global block, anything it didn't resolve is looked for everywhere.
+func (env Environment) lookupFrom(local *local) int {
+	if local != nil && local.resolved && local.depth < 0 {
+		return 0
+	}
+	return len(env.vars) - 1
 }
 
-func (env Environment) arrayStore(identifier string, arr []*Value) {
-	/* Currently 'arr' is an array of pointers to Value interfaces. What we need to store into the varMap is a Value pointer */
-	/* As far as I'm concerned, magic is the only way that's going to happen so that's exactly what'll be done */
-	x := magic(arr)
-	env.vars[len(env.vars)-1].store.(varMap)[identifier] = &x
+func (env Environment) classStore(class JlangClass) {
+	env.varStore(class.identifier.Lexeme, classValue(&class))
 }
 
 func (env *Environment) pop() {
 	env.vars = env.vars[:len(env.vars)-1]
-	env.funcs = env.funcs[:len(env.funcs)-1]
 }
 
 func (env *Environment) push(blockID string) {
-	env.vars = append(env.vars, Block{"var-" + blockID, make(varMap)})
-	env.funcs = append(env.funcs, Block{"fun-" + blockID, make(funcMap)})
+	env.vars = append(env.vars, Block{blockID, newVarMap()})
 }
 
-func NewEnvironment(id string) Environment {
-	env := Environment{make([]Block, 1), make([]Block, 1)}
-	env.vars[0] = Block{id, make(varMap)}
-	env.funcs[0] = Block{id, make(funcMap)}
-	return env
+// capture is a snapshot of the blocks currently in scope for a closure.
+// The blocks themselves are shared so their variables stay in sync, but pushing and popping on either side isn't seen by the other.
+func (env Environment) capture() Environment {
+	vars := make([]Block, len(env.vars))
+	copy(vars, env.vars)
+	return Environment{vars}
 }
 
-// I don't know what the implications of this magic are
-func magic(x interface{}) Value {
-	return x
+func NewEnvironment(id string) Environment {
+	env := Environment{make([]Block, 1)}
+	env.vars[0] = Block{id, newVarMap()}
+	return env
 }
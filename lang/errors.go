@@ -12,7 +12,7 @@ type BadMethodInvocation struct {
 }
 
 func (err BadMethodInvocation) Error() string {
-	return fmt.Sprintf("Bad invocation of '%s':\n\tmore: %s", err.identifier.Lexeme, err.subject)
+	return annotate(fmt.Sprintf("Bad invocation of '%s':\n\tmore: %s", err.identifier.Lexeme, err.subject), err.identifier)
 }
 
 type BadPropertyAssignmentType struct {
@@ -21,7 +21,7 @@ type BadPropertyAssignmentType struct {
 }
 
 func (err BadPropertyAssignmentType) Error() string {
-	return fmt.Sprintf("Type '%s' does not implement property assignment on assigning member '%s'.", err.tipe, err.identifier.Lexeme)
+	return annotate(fmt.Sprintf("Type '%s' does not implement property assignment on assigning member '%s'.", err.tipe, err.identifier.Lexeme), err.identifier)
 }
 
 type BadPropertyAccess struct {
@@ -30,7 +30,7 @@ type BadPropertyAccess struct {
 }
 
 func (err BadPropertyAccess) Error() string {
-	return fmt.Sprintf("Invalid property access at %d: %s.", err.propId.Line, err.reason)
+	return annotate(fmt.Sprintf("Invalid property access at %d: %s.", err.propId.Line, err.reason), err.propId)
 }
 
 type InvalidClassStatement struct {
@@ -39,17 +39,38 @@ type InvalidClassStatement struct {
 }
 
 func (err InvalidClassStatement) Error() string {
-	return fmt.Sprintf("Not a valid class statement at '%s' in class '%s' on %d.", err.src.Lexeme, err.class.Lexeme, err.src.Line)
+	return annotate(fmt.Sprintf("Not a valid class statement at '%s' in class '%s' on %d.", err.src.Lexeme, err.class.Lexeme, err.src.Line), err.src)
+}
+
+// InvalidSuperclass is when a class inherits from a value that isn't a class
+type InvalidSuperclass struct {
+	class      Token
+	superclass Token
+}
+
+func (err InvalidSuperclass) Error() string {
+	return annotate(fmt.Sprintf("Class '%s' can't inherit from '%s' since it isn't a class.", err.class.Lexeme, err.superclass.Lexeme), err.superclass)
+}
+
+// BadIndex is indexing something that isn't an array or map, or indexing with something that can't index it
+type BadIndex struct {
+	at     Token
+	reason error
+}
+
+func (err BadIndex) Error() string {
+	return annotate(fmt.Sprintf("Invalid index: %s.", err.reason), err.at)
 }
 
 type OutOfBounds struct {
 	arrLex string
 	index  int
 	actual int
+	at     Token
 }
 
 func (err OutOfBounds) Error() string {
-	return fmt.Sprintf("Out of bounds access on array '%s'\n\t'%s' is length %d while the index was %d", err.arrLex, err.arrLex, err.actual, err.index)
+	return annotate(fmt.Sprintf("Out of bounds access on array '%s'\n\t'%s' is length %d while the index was %d", err.arrLex, err.arrLex, err.actual, err.index), err.at)
 }
 
 type DivisionByZero struct {
@@ -57,7 +78,8 @@ type DivisionByZero struct {
 }
 
 func (err DivisionByZero) Error() string {
-	return fmt.Sprintf("Divide by zero error at '%s'.", err.offender)
+	at := spanOf(err.offender)
+	return annotate(fmt.Sprintf("Divide by zero error at '%s'.", at.Lexeme), at)
 }
 
 type ArgumentMismatch struct {
@@ -67,7 +89,15 @@ type ArgumentMismatch struct {
 }
 
 func (err ArgumentMismatch) Error() string {
-	return fmt.Sprintf("argument length mismatch for '%s' call: want %d, got %d.", err.identifier.Lexeme, err.expected, err.got)
+	return annotate(err.message(), err.identifier)
+}
+
+// message is the mismatch without an excerpt, it names the line the function was declared on unless it's a builtin
+func (err ArgumentMismatch) message() string {
+	if err.identifier.src == nil {
+		return fmt.Sprintf("argument length mismatch for '%s' call: want %d, got %d.", err.identifier.Lexeme, err.expected, err.got)
+	}
+	return fmt.Sprintf("argument length mismatch for '%s' call: want %d, got %d (declared on line %d).", err.identifier.Lexeme, err.expected, err.got, err.identifier.Line)
 }
 
 type BadCall struct {
@@ -76,10 +106,14 @@ type BadCall struct {
 }
 
 func (err BadCall) Error() string {
+	// Only the call site gets an excerpt, the declaration is just named
+	if mismatch, ok := err.more.(ArgumentMismatch); ok {
+		return annotate(fmt.Sprintf("bad call to '%s'\n\tmore: %s", err.id.Lexeme, mismatch.message()), err.id)
+	}
 	if err.more != nil {
-		return fmt.Sprintf("bad call to '%s'\n\tmore: %s", err.id.Lexeme, err.more)
+		return annotate(fmt.Sprintf("bad call to '%s'\n\tmore: %s", err.id.Lexeme, err.more), err.id)
 	}
-	return fmt.Sprintf("unable to reference unknown '%s'", err.id.Lexeme)
+	return annotate(fmt.Sprintf("unable to reference unknown '%s'", err.id.Lexeme), err.id)
 }
 
 type InvalidOperation struct {
@@ -87,7 +121,7 @@ type InvalidOperation struct {
 }
 
 func (err InvalidOperation) Error() string {
-	return fmt.Sprintf("Invalid type operation '%s' on line %d", err.op.Lexeme, err.op.Line)
+	return annotate(fmt.Sprintf("Invalid type operation '%s' on line %d", err.op.Lexeme, err.op.Line), err.op.Token)
 }
 
 type NilReference struct {
@@ -95,7 +129,7 @@ type NilReference struct {
 }
 
 func (err NilReference) Error() string {
-	return fmt.Sprintf("Reference to nil value on line %d at '%s'", err.reference.Line, err.reference.Lexeme)
+	return annotate(fmt.Sprintf("Reference to nil value on line %d at '%s'", err.reference.Line, err.reference.Lexeme), err.reference)
 }
 
 type UnknownIdentifier struct {
@@ -103,17 +137,171 @@ type UnknownIdentifier struct {
 }
 
 func (err UnknownIdentifier) Error() string {
-	return fmt.Sprintf("Unable to reference unknown variable '%s' on line %d.", err.Lexeme, err.Line)
+	return annotate(fmt.Sprintf("Unable to reference unknown variable '%s' on line %d.", err.Lexeme, err.Line), err.Token)
+}
+
+// UndefinedVariable is a variable that isn't declared in any scope it's used from, found before the program runs
+type UndefinedVariable struct {
+	Token
+}
+
+func (err UndefinedVariable) Error() string {
+	return annotate(fmt.Sprintf("Undefined variable '%s' on line %d.", err.Lexeme, err.Line), err.Token)
+}
+
+// UseBeforeDeclare is a variable that's used in a scope before it's declared in that scope
+type UseBeforeDeclare struct {
+	reference   Token
+	declaration Token
+}
+
+func (err UseBeforeDeclare) Error() string {
+	return annotate(fmt.Sprintf("Variable '%s' is used on line %d before it's declared on line %d.", err.reference.Lexeme, err.reference.Line, err.declaration.Line), err.reference)
+}
+
+// DuplicateDeclaration is a name that's declared twice in the same scope
+type DuplicateDeclaration struct {
+	declaration Token
+	previous    Token
+}
+
+func (err DuplicateDeclaration) Error() string {
+	return annotate(fmt.Sprintf("'%s' is already declared in this scope on line %d.", err.declaration.Lexeme, err.previous.Line), err.declaration)
+}
+
+// UnusedVariable is a local variable that's declared but never used, which is a warning instead of an error
+type UnusedVariable struct {
+	Token
+}
+
+func (err UnusedVariable) Error() string {
+	return annotate(fmt.Sprintf("Local variable '%s' is declared but never used.", err.Lexeme), err.Token)
 }
 
 type InvalidTypeCombination struct {
 	Operation string
-	Left      reflect.Kind
-	Rite      reflect.Kind
+	Left      ValueKind
+	Rite      ValueKind
+	op        Operator
 }
 
 func (err InvalidTypeCombination) Error() string {
-	return fmt.Sprintf("Invalid %s between type %s and %s.", err.Operation, err.Left, err.Rite)
+	return annotate(fmt.Sprintf("Invalid %s between type %s and %s.", err.Operation, err.Left, err.Rite), err.op.Token)
+}
+
+// Thrown is a value a 'throw' raised that nothing caught
+type Thrown struct {
+	keyword Token
+	value   Value
+}
+
+func (err Thrown) Error() string {
+	msg := err.value.String()
+	if err.value.kind == InstanceKind {
+		// Errors are shown as 'kind: message' rather than all their members
+		instance := err.value.asInstance()
+		kind, hasKind := instance.member("kind")
+		message, hasMessage := instance.member("message")
+		if hasKind && hasMessage {
+			msg = fmt.Sprintf("%s: %s", kind, message)
+		}
+	}
+	return annotate(fmt.Sprintf("Uncaught %s", msg), err.keyword)
+}
+
+// Traceback is a runtime error that escaped from a call, with the calls it was raised in
+type Traceback struct {
+	Err    error
+	Frames []Frame // Innermost first
+}
+
+// Frame is a call in a Traceback
+type Frame struct {
+	Function string // i.e 'area', 'Rect#area' or 'func' for a function without a name
+	Site     Token  // Where it was called from
+}
+
+// Error is the error followed by where each call was at when it was raised i.e
+//
+//	at area (shapes.jlang:4:16)
+//	at <script> (shapes.jlang:12:7)
+func (err Traceback) Error() string {
+	str := strings.Builder{}
+	str.WriteString(err.Err.Error() + "\nTraceback (most recent call first):")
+	lines := traceLines(err.Frames, errorToken(err.Err))
+	period, repeats := cycle(lines), 1
+	for (repeats+1)*period <= len(lines) && equalLines(lines[:period], lines[repeats*period:]) {
+		repeats++
+	}
+	// A recursion is only shown once however many times it went around
+	if repeats > 2 {
+		lines = append(lines[:period:period], lines[repeats*period:]...)
+		lines[period-1] += fmt.Sprintf("\n\t... the %d calls above repeat %d more times", period, repeats-1)
+	}
+	for _, line := range lines {
+		str.WriteString(line)
+	}
+
+	at := errorToken(err.Err)
+	if len(err.Frames) > 0 {
+		at = err.Frames[len(err.Frames)-1].Site
+	}
+	str.WriteString(traceLine("<script>", at))
+	return str.String()
+}
+
+func (err Traceback) Unwrap() error {
+	return err.Err
+}
+
+// StackOverflow is a call that goes deeper than the Interpreter's max depth, which is usually a recursion that never stops
+type StackOverflow struct {
+	site   Token
+	depth  int
+	frames []Frame // The calls that keep repeating, innermost first
+}
+
+func (err StackOverflow) Error() string {
+	msg := fmt.Sprintf("Stack overflow: more than %d calls deep, repeating:", err.depth)
+	return annotate(msg+strings.Join(traceLines(err.frames, err.site), ""), err.site)
+}
+
+// traceLines is a line for each frame with the position it was at, which is at for the innermost one
+func traceLines(frames []Frame, at Token) []string {
+	lines := make([]string, len(frames))
+	for i, frame := range frames {
+		lines[i] = traceLine(frame.Function, at)
+		at = frame.Site
+	}
+	return lines
+}
+
+// traceLine is a function in a Traceback and the position it was at, which builtins and errors without a Token don't have
+func traceLine(function string, at Token) string {
+	if at.Line == 0 {
+		return "\n\tat " + function
+	}
+	return fmt.Sprintf("\n\tat %s (%s)", function, at.Position())
+}
+
+// cycle is how many of the innermost lines of a trace repeat straight after themselves, or 1 if none do
+func cycle(lines []string) int {
+	for period := 1; period <= len(lines)/2; period++ {
+		if equalLines(lines[:period], lines[period:]) {
+			return period
+		}
+	}
+	return 1
+}
+
+// equalLines is whether r starts with the same lines as l
+func equalLines(l, r []string) bool {
+	for i := range l {
+		if l[i] != r[i] {
+			return false
+		}
+	}
+	return true
 }
 
 type ScanError struct {
@@ -131,29 +319,57 @@ type ParseError struct {
 
 func (err ParseError) Error() string {
 	if err.token.is(EOF) {
-		return fmt.Sprintf("%d at end %s", err.token.Line, err.msg)
+		return annotate(fmt.Sprintf("%d at end %s", err.token.Line, err.msg), err.token)
 	} else {
-		return fmt.Sprintf("%d at '%s' %s", err.token.Line, err.token.Lexeme, err.msg)
+		return annotate(fmt.Sprintf("%d at '%s' %s", err.token.Line, err.token.Lexeme, err.msg), err.token)
 	}
 }
 
 // UnclosedStringError is when the scanner is attempting to scan a string lexeme but never reaches a closing (right) closing '"'
 type UnclosedString struct {
-	line uint
+	token Token
+	quote rune // What was expected to close the string, or '}' for an interpolated expression
 }
 
 func (err UnclosedString) Error() string {
-	return fmt.Sprintf("[UnclosedString] expected \" for string on line %d\n", err.line)
+	return annotate(fmt.Sprintf("[UnclosedString] expected %c for string on line %d", err.quote, err.token.Line), err.token)
+}
+
+// UnclosedComment is a '/*' block comment that never reaches its closing '*/'
+type UnclosedComment struct {
+	token Token
+}
+
+func (err UnclosedComment) Error() string {
+	return annotate(fmt.Sprintf("[UnclosedComment] expected */ for comment on line %d", err.token.Line), err.token)
+}
+
+// InvalidEscape is a '\' in a string that isn't followed by a valid escape sequence
+type InvalidEscape struct {
+	token Token
+}
+
+func (err InvalidEscape) Error() string {
+	return annotate(fmt.Sprintf("[InvalidEscape] invalid escape sequence '%s' in string on line %d", err.token.Lexeme, err.token.Line), err.token)
+}
+
+// InvalidNumber is a malformed Number literal i.e '1.2.3', '0b102' or '1__000'
+type InvalidNumber struct {
+	token  Token
+	reason string
+}
+
+func (err InvalidNumber) Error() string {
+	return annotate(fmt.Sprintf("[InvalidNumber] invalid number '%s' on line %d: %s", err.token.Lexeme, err.token.Line, err.reason), err.token)
 }
 
 // UnknownToken is when we encounter a lexeme we don't have a matching token for
 type UnknownToken struct {
-	lexeme string
-	line   int
+	token Token
 }
 
 func (err UnknownToken) Error() string {
-	return fmt.Sprintf("unknown token %s on line %d", err.lexeme, err.line)
+	return annotate(fmt.Sprintf("unknown token %s on line %d", err.token.Lexeme, err.token.Line), err.token)
 }
 
 // UnknownFile is when trying to verify that a file exists (before opening for r/w),
@@ -177,19 +393,92 @@ func (err FileReadFailure) Error() string {
 	return fmt.Sprintf("failure to read file %s: %s", err.Filepath, err.Err)
 }
 
+// ErrorList is every error that was found in a single pass, i.e all the syntax errors in a file
 type ErrorList struct {
 	errs []error
 }
 
 func (list ErrorList) Error() string {
 	str := strings.Builder{}
-	str.WriteString("Errors: ")
+	str.WriteString(fmt.Sprintf("Errors (%d):", len(list.errs)))
 	for _, err := range list.errs {
-		str.WriteString(err.Error() + "\n")
+		str.WriteString("\n" + err.Error())
 	}
 	return str.String()
 }
 
+// Errors is each error in the order it was found
+func (list ErrorList) Errors() []error {
+	return list.errs
+}
+
+// errorToken is the Token an error points at, or an empty Token for errors that don't point at one
+func errorToken(err error) Token {
+	switch e := err.(type) {
+	case UndefinedVariable:
+		return e.Token
+	case UseBeforeDeclare:
+		return e.reference
+	case DuplicateDeclaration:
+		return e.declaration
+	case BadMethodInvocation:
+		return e.identifier
+	case BadPropertyAssignmentType:
+		return e.identifier
+	case BadPropertyAccess:
+		return e.propId
+	case InvalidSuperclass:
+		return e.superclass
+	case BadIndex:
+		return e.at
+	case OutOfBounds:
+		return e.at
+	case DivisionByZero:
+		return spanOf(e.offender)
+	case ArgumentMismatch:
+		return e.identifier
+	case BadCall:
+		return e.id
+	case InvalidOperation:
+		return e.op.Token
+	case NilReference:
+		return e.reference
+	case UnknownIdentifier:
+		return e.Token
+	case InvalidTypeCombination:
+		return e.op.Token
+	case Thrown:
+		return e.keyword
+	case StackOverflow:
+		return e.site
+	}
+	return Token{}
+}
+
+// errorKind is the name of an error's type i.e 'OutOfBounds', or just 'Error' for errors that aren't one of ours
+func errorKind(err error) string {
+	if name := reflect.TypeOf(err).Name(); name != "" {
+		return name
+	}
+	return "Error"
+}
+
+// errorMessage is an error's message without the excerpt of the source it points at
+func errorMessage(err error) string {
+	if excerpt := errorToken(err).excerpt(); excerpt != "" {
+		return strings.TrimSuffix(err.Error(), "\n"+excerpt)
+	}
+	return err.Error()
+}
+
+// annotate appends the excerpt of the source at the offending Token to an error message
+func annotate(msg string, at Token) string {
+	if excerpt := at.excerpt(); excerpt != "" {
+		return msg + "\n" + excerpt
+	}
+	return msg
+}
+
 // InternalError is a generic error type for any subsystem to return on a failure of some
 // generic functionality.
 /*
@@ -199,6 +488,10 @@ func (list ErrorList) Error() string {
 |  1  | nil                                    |
 | ... |      				  				   |
 | 10  | scanner.seek() reached end of file      |
+| 11  | compiled code is out of range of a VM   |
+|     | instruction's operand                   |
+| 12  | the VM ran into an opcode it doesn't    |
+|     | know                                    |
 +----------------------------------------------+
 */
 type InternalError struct {
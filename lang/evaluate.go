@@ -2,8 +2,6 @@ package lang
 
 import (
 	"fmt"
-	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -15,70 +13,60 @@ func (grouping Grouping) evaluate(intptr *Interpreter) (Value, error) {
 
 func (unary Unary) evaluate(intptr *Interpreter) (Value, error) {
 	if unary.Expr == nil {
-		return nil, InvalidOperation{unary.Op}
+		return Value{}, InvalidOperation{unary.Op}
 	}
 	expr, err := unary.Expr.evaluate(intptr)
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
+	return unary.apply(expr)
+}
+
+// apply is the operator applied to the already evaluated operand
+func (unary Unary) apply(expr Value) (Value, error) {
+	var val Value
+	ok := false
 	switch unary.Op.Type {
 	case Minus:
-		switch reflect.TypeOf(expr).Kind() {
-		case reflect.Int:
-			return -expr.(int), nil
-		case reflect.Float64:
-			return -expr.(float64), nil
-		}
+		val, ok = expr.negate()
 	case Bang:
-		switch reflect.TypeOf(expr).Kind() {
-		case reflect.Bool:
-			return !expr.(bool), nil
-		}
+		val, ok = expr.not()
 	case PlusPlus:
-		kind := reflect.TypeOf(expr).Kind()
-		if kind == reflect.Float64 {
-			return expr.(float64) + 1, nil
-		} else if kind == reflect.Int {
-			return expr.(int) + 1, nil
-		}
+		val, ok = expr.step(1)
 	case MinusMinus:
-		kind := reflect.TypeOf(expr).Kind()
-		if kind == reflect.Float64 {
-			return expr.(float64) - 1, nil
-		} else if kind == reflect.Int {
-			return expr.(int) - 1, nil
-		}
+		val, ok = expr.step(-1)
 	}
-
-	return nil, InvalidOperation{unary.Op}
+	if !ok {
+		return Value{}, InvalidOperation{unary.Op}
+	}
+	return val, nil
 }
 
 func (binary Binary) evaluate(intptr *Interpreter) (Value, error) {
 	left, err := binary.Left.evaluate(intptr)
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
 	right, err := binary.Right.evaluate(intptr)
 	if err != nil {
-		return nil, err
+		return Value{}, err
 	}
+	return binary.apply(intptr, left, right)
+}
 
-	if left == nil || right == nil {
+// apply is the operator applied to the already evaluated operands. intptr is for calling 'equals' methods.
+func (binary Binary) apply(intptr *Interpreter, left Value, right Value) (Value, error) {
+	if left.isNil() || right.isNil() {
 		switch binary.Op.Type {
 		case EqualEqual:
-			if left == nil {
-				return right == nil, nil
-			}
-			return false, nil
+			return boolValue(left.isNil() && right.isNil()), nil
 		case BangEqual:
-			if left == nil {
-				return !(right == nil), nil
-			} else if right == nil {
-				return true, nil
-			}
-			return false, nil
+			return boolValue(left.isNil() != right.isNil()), nil
+		}
+		if left.isNil() {
+			return Value{}, NilReference{spanOf(binary.Left)}
 		}
-		return nil, NilReference{}
+		return Value{}, NilReference{spanOf(binary.Right)}
 	}
 
 	switch binary.Op.Type {
@@ -91,205 +79,149 @@ func (binary Binary) evaluate(intptr *Interpreter) (Value, error) {
 	case Slash:
 		return binary.divide(left, right)
 	case Greater:
-		return binary.Greater(left, right), nil
+		return binary.order(right, left, false)
 	case Less:
-		return binary.Less(left, right), nil
+		return binary.order(left, right, false)
 	case GreaterEqual:
-		if equal(left, right) {
-			return true, nil
-		}
-		return binary.Greater(left, right), nil
+		return binary.order(right, left, true)
 	case LessEqual:
-		if equal(left, right) {
-			return true, nil
-		}
-		return !binary.Greater(left, right), nil
+		return binary.order(left, right, true)
 	case EqualEqual:
-		return binary.Equality(left, right)
+		return binary.Equality(intptr, left, right)
 	case BangEqual:
-		return binary.Inequality(left, right)
+		return binary.Inequality(intptr, left, right)
 	case Mod:
 		return binary.Modulo(left, right)
 	}
 
-	return nil, InvalidOperation{binary.Op}
+	return Value{}, InvalidOperation{binary.Op}
 }
 
-func (binary Binary) Greater(lhs Value, rhs Value) bool {
-	left, right := getLeftRightKinds(lhs, rhs)
-	switch left {
-	case reflect.Int:
-		if right == reflect.Int {
-			return lhs.(int) > rhs.(int)
-		} else if right == reflect.Float64 {
-			return float64(lhs.(int)) > rhs.(float64)
+// evaluate only evaluates the right hand side if the left hand side doesn't already decide the result.
+// The result is the operand that decided it rather than a bool, i.e 'nil or "default"' is "default".
+func (logical Logical) evaluate(intptr *Interpreter) (Value, error) {
+	left, err := logical.Left.evaluate(intptr)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch logical.Op.Type {
+	case Or:
+		if truthy(left) {
+			return left, nil
 		}
-	case reflect.Float64:
-		if right == left {
-			return lhs.(float64) > rhs.(float64)
-		} else if right == reflect.Int {
-			return lhs.(float64) > float64(rhs.(int))
+	case And:
+		if !truthy(left) {
+			return left, nil
 		}
+	default:
+		return Value{}, InvalidOperation{logical.Op}
 	}
 
-	return false
+	return logical.Right.evaluate(intptr)
 }
 
-func (binary Binary) Less(lhs Value, rhs Value) bool {
-	left, right := getLeftRightKinds(lhs, rhs)
-	switch left {
-	case reflect.Int:
-		if right == reflect.Int {
-			return lhs.(int) < rhs.(int)
-		} else if right == reflect.Float64 {
-			return float64(lhs.(int)) < rhs.(float64)
-		}
-	case reflect.Float64:
-		if right == left {
-			return lhs.(float64) < rhs.(float64)
-		} else if right == reflect.Int {
-			return lhs.(float64) < float64(rhs.(int))
+// order is whether lhs comes before rhs, or is equal to it when orEqual. The operands are swapped for '>' and '>='.
+func (binary Binary) order(lhs Value, rhs Value, orEqual bool) (Value, error) {
+	before, ok := lhs.before(rhs, orEqual, nil)
+	if !ok {
+		if binary.Op.is(Greater) || binary.Op.is(GreaterEqual) {
+			lhs, rhs = rhs, lhs
 		}
+		return Value{}, binary.invalid("comparison", lhs, rhs)
 	}
-
-	return false
+	return boolValue(before), nil
 }
 
 func (binary Binary) plus(left Value, right Value) (Value, error) {
-	lKind, rKind := getLeftRightKinds(left, right)
-	switch lKind {
-	case reflect.Int:
-		if lKind == rKind {
-			return left.(int) + right.(int), nil
-		} else if rKind == reflect.Float64 {
-			return float64(left.(int)) + right.(float64), nil
-		} else if rKind == reflect.String {
-			return fmt.Sprintf("%v%v", left, right), nil
-		}
-	case reflect.String:
-		return fmt.Sprint(left, right), nil
-	case reflect.Bool:
-		if lKind == rKind {
-			if left.(bool) && right.(bool) {
-				return true, nil
-			}
-			return false, nil
-		}
-	case reflect.Float64:
-		if lKind == rKind {
-			return left.(float64) + right.(float64), nil
-		} else if rKind == reflect.Int {
-			return left.(float64) + float64(right.(int)), nil
-		}
+	if val, ok := left.plus(right); ok {
+		return val, nil
 	}
-
-	return nil, InvalidTypeCombination{"addition", lKind, rKind}
+	return Value{}, binary.invalid("addition", left, right)
 }
 
 func (binary Binary) minus(left Value, right Value) (Value, error) {
-	lKind, rKind := getLeftRightKinds(left, right)
-	switch lKind {
-	case reflect.Int:
-		if lKind == rKind {
-			return left.(int) - right.(int), nil
-		} else if rKind == reflect.Float64 {
-			return float64(left.(int)) - right.(float64), nil
-		}
-	case reflect.Float64:
-		if lKind == rKind {
-			return left.(float64) - right.(float64), nil
-		} else if rKind == reflect.Int {
-			return left.(float64) - float64(right.(int)), nil
-		}
+	if val, ok := left.minus(right); ok {
+		return val, nil
 	}
-	return nil, InvalidTypeCombination{"subtraction", lKind, rKind}
+	return Value{}, binary.invalid("subtraction", left, right)
 }
 
 func (binary Binary) multiply(left Value, right Value) (Value, error) {
-	lKind, rKind := getLeftRightKinds(left, right)
-	switch lKind {
-	case reflect.Int:
-		if rKind == lKind {
-			return left.(int) * right.(int), nil
-		} else if rKind == reflect.Float64 {
-			return float64(left.(int)) * right.(float64), nil
-		}
-	case reflect.Float64:
-		if rKind == lKind {
-			return left.(float64) * right.(float64), nil
-		} else if rKind == reflect.Int {
-			return left.(float64) * float64(right.(int)), nil
-		}
+	if val, ok := left.times(right); ok {
+		return val, nil
 	}
-	return nil, InvalidTypeCombination{"multiplication", lKind, rKind}
+	return Value{}, binary.invalid("multiplication", left, right)
 }
 
 func (binary Binary) divide(left Value, right Value) (Value, error) {
-	if left == 0 {
-		return nil, DivisionByZero{binary.Left}
-	} else if right == 0 {
-		return nil, DivisionByZero{binary.Right}
-	}
-
-	lKind, rKind := getLeftRightKinds(left, right)
-	switch lKind {
-	case reflect.Int:
-		if rKind == reflect.Int {
-			return left.(int) / right.(int), nil
-		} else if rKind == reflect.Float64 {
-			return float64(left.(int)) / right.(float64), nil
-		}
-	case reflect.Float64:
-		if rKind == lKind {
-			return left.(float64) / right.(float64), nil
-		} else if rKind == reflect.Int {
-			return left.(float64) / float64(right.(int)), nil
-		}
+	if left.kind == IntKind && left.asInt() == 0 {
+		return Value{}, DivisionByZero{binary.Left}
+	} else if right.kind == IntKind && right.asInt() == 0 {
+		return Value{}, DivisionByZero{binary.Right}
 	}
-
-	return nil, InvalidTypeCombination{"division", lKind, rKind}
+	if val, ok := left.divide(right); ok {
+		return val, nil
+	}
+	return Value{}, binary.invalid("division", left, right)
 }
 
 func (binary Binary) Modulo(left Value, right Value) (Value, error) {
-	lKind, rKind := getLeftRightKinds(left, right)
-	switch lKind {
-	case reflect.Int:
-		if rKind == reflect.Int {
-			return left.(int) % right.(int), nil
-		}
+	if left.kind == IntKind && right.kind == IntKind && right.asInt() == 0 {
+		return Value{}, DivisionByZero{binary.Right}
+	}
+	if val, ok := left.modulo(right); ok {
+		return val, nil
 	}
+	return Value{}, binary.invalid("modulo", left, right)
+}
 
-	return nil, InvalidTypeCombination{"modulo", lKind, rKind}
+// invalid is the error for an operation on operands whose types don't support it
+func (binary Binary) invalid(operation string, left Value, right Value) error {
+	return InvalidTypeCombination{operation, left.kind, right.kind, binary.Op}
 }
 
-func (binary Binary) Equality(left Value, right Value) (Value, error) {
-	return equal(left, right), nil
+func (binary Binary) Equality(intptr *Interpreter, left Value, right Value) (Value, error) {
+	eq, err := left.equal(intptr, binary.Op.Token, right)
+	return boolValue(eq), err
 }
 
-func (binary Binary) Inequality(left Value, right Value) (Value, error) {
-	return !equal(left, right), nil
+func (binary Binary) Inequality(intptr *Interpreter, left Value, right Value) (Value, error) {
+	eq, err := left.equal(intptr, binary.Op.Token, right)
+	return boolValue(!eq), err
 }
 
 func (literal Literal) evaluate(intptr *Interpreter) (Value, error) {
 	switch literal.Type {
 	case Number:
-		if strings.Contains(literal.Lexeme, ".") {
-			return strconv.ParseFloat(literal.Lexeme, 64)
-		}
-		return strconv.Atoi(literal.Lexeme)
+		// The scanner already parsed it
+		return literal.value, nil
 	case String:
-		return literal.Lexeme, nil
+		return stringValue(literal.Lexeme), nil
 	case True:
-		return true, nil
+		return boolValue(true), nil
 	case False:
-		return false, nil
+		return boolValue(false), nil
 	case Nil:
-		return nil, nil
+		return Value{}, nil
 	case EOF:
-		return "EOF", nil
+		return stringValue("EOF"), nil
 	}
 
-	return nil, fmt.Errorf("Unable to match literal %s, with a known value.", literal.Token.Lexeme)
+	return Value{}, fmt.Errorf("Unable to match literal %s, with a known value.", literal.Token.Lexeme)
+}
+
+// evaluate is every part of the string joined together, the expressions are shown the same as they would be printed
+func (interp Interpolation) evaluate(intptr *Interpreter) (Value, error) {
+	str := strings.Builder{}
+	for _, part := range interp.parts {
+		val, err := part.evaluate(intptr)
+		if err != nil {
+			return Value{}, err
+		}
+		str.WriteString(val.String())
+	}
+	return stringValue(str.String()), nil
 }
 
 func (variable Variable) evaluate(intptr *Interpreter) (Value, error) {
@@ -297,127 +229,204 @@ func (variable Variable) evaluate(intptr *Interpreter) (Value, error) {
 }
 
 func (call Call) evaluate(intptr *Interpreter) (Value, error) {
-	val, err := call.asFunctionCall().evaluate(intptr)
-	if err == nil {
-		return val, nil
-	}
-	if class, err := intptr.env.classResolve(call.identifier); err == nil {
-		if class.constructor != nil {
-			class.constructor.argExprs = call.args
-		}
-		if instance, err := class.evaluate(intptr); err == nil {
-			return instance, nil
-		} else {
-			return nil, err
+	callee, err := call.callee.evaluate(intptr)
+	if err != nil {
+		if _, isVariable := call.callee.(Variable); isVariable {
+			if unknown, ok := err.(UnknownIdentifier); ok {
+				return Value{}, BadCall{unknown.Token, nil}
+			}
 		}
+		return Value{}, err
+	}
+	args, err := evaluateArgs(intptr, call.args)
+	if err != nil {
+		return Value{}, err
 	}
 
-	return nil, BadCall{call.identifier, err}
+	return intptr.callValue(callee, spanOf(call.callee), args)
 }
 
-func (call *Call) asFunctionCall() FunctionCall {
-	return FunctionCall{call.identifier, call.args}
+// evaluate creates the function value, closing over the scope the expression is evaluated in
+func (lambda Lambda) evaluate(intptr *Interpreter) (Value, error) {
+	return functionValue(JlangFunction{lambda.decl, intptr.env.capture(), nil, nil}), nil
 }
 
-func (fun FunctionCall) evaluate(intptr *Interpreter) (Value, error) {
-	intptr.env.push(fmt.Sprintf("%s@%d", fun.identifier.Lexeme, fun.identifier.Line))
-	defer intptr.env.pop()
-	return intptr.FunctionResolve(fun)
+// evaluate binds the superclass method, or constructor, to the 'this' of the method 'super' is used in
+func (super SuperAccess) evaluate(intptr *Interpreter) (Value, error) {
+	superclass, err := intptr.VariableResolver(Variable{super.keyword, nil})
+	if err != nil {
+		return Value{}, err
+	}
+	this, err := intptr.VariableResolver(Variable{thisToken(super.keyword.Line), nil})
+	if err != nil {
+		return Value{}, err
+	}
+	return super.bind(superclass, this)
 }
 
-func (fun FunctionInvocation) evaluate(intptr *Interpreter) (Value, error) {
-	if fun.arity != fun.stmt.arity {
-		return nil, ArgumentMismatch{fun.stmt.Identifier, fun.stmt.arity, fun.arity}
-	}
+// bind is the superclass method, or constructor, bound to this
+func (super SuperAccess) bind(superclass Value, this Value) (Value, error) {
+	class, instance := superclass.asClass(), this.asInstance()
 
-	if fun.stmt.arity != 0 || (fun.stmt.args != nil && *fun.stmt.args != nil) {
-		// If we have args, map them to the interpreter environment
-		for i, expr := range *fun.argExprs { // We can assume this is a safe dereference due to the arity check
-			intptr.VariableMap(VariableStatement{(*fun.stmt.args)[i], expr})
+	if super.method == nil {
+		if constructor := class.findConstructor(); constructor != nil {
+			return functionValue(constructor.bind(instance)), nil
 		}
+		return Value{}, BadMethodInvocation{super.keyword, fmt.Errorf("superclass '%s' has no constructor.", class.identifier.Lexeme)}
 	}
+	if method, found := class.findMethod(super.method.Lexeme); found {
+		return functionValue(method.bind(instance)), nil
+	}
+	return Value{}, BadMethodInvocation{*super.method, fmt.Errorf("unresolved method '%s' for superclass '%s'.", super.method.Lexeme, class.identifier.Lexeme)}
+}
 
-	for _, stmt := range fun.stmt.block {
-		if err := stmt.execute(intptr); err != nil {
-			return nil, err
-		}
-		if intptr.shouldBreak() {
-			break
-		}
+func (method MethodInvocation) evaluate(intptr *Interpreter) (Value, error) {
+	object, err := method.this.evaluate(intptr)
+	if err != nil {
+		return Value{}, err
+	}
+	if err := method.check(object); err != nil {
+		return Value{}, err
 	}
 
-	if intptr.funcRet != nil {
-		val := *intptr.funcRet
-		intptr.funcRet = nil
-		return val, nil
+	args, err := evaluateArgs(intptr, method.argExprs)
+	if err != nil {
+		return Value{}, err
 	}
+	return object.asInstance().invoke(intptr, method.identifier, args)
+}
 
-	fun.arity = 0
-	return nil, nil
+// check makes sure the method is being invoked on an instance, before the arguments are evaluated
+func (method MethodInvocation) check(object Value) error {
+	if object.kind != InstanceKind {
+		return BadMethodInvocation{method.identifier, fmt.Errorf("type '%s' does not implement method invocation.", object.kind)}
+	}
+	return nil
 }
 
-func (fun *FunctionInvocation) FillArgs(argExprs *[]Expression) {
-	if argExprs == nil {
-		return
+func (prop PropertyAccess) evaluate(intptr *Interpreter) (Value, error) {
+	val, err := prop.Expr.evaluate(intptr)
+	if err != nil {
+		return Value{}, err
 	}
-	if len(*argExprs) == 0 {
-		return
+	return prop.access(intptr, val)
+}
+
+// access is the property of the already evaluated object
+func (prop PropertyAccess) access(intptr *Interpreter, val Value) (Value, error) {
+	switch val.kind {
+	case InstanceKind:
+		return val.asInstance().propertyAccess(prop.identifier)
+	case ClassKind:
+		return val.asClass().call(intptr, prop.identifier, nil)
 	}
 
-	fun.argExprs = argExprs
+	return Value{}, BadPropertyAccess{prop.identifier, fmt.Errorf("type '%s' does not implement property access", val.kind)}
 }
 
-func (method MethodInvocation) evaluate(intptr *Interpreter) (Value, error) {
-	object, err := method.this.evaluate(intptr)
-	if err != nil {
-		return nil, err
+func (array ArrayLiteral) evaluate(intptr *Interpreter) (Value, error) {
+	vals := make([]Value, 0, len(array.elements))
+	for _, expr := range array.elements {
+		val, err := expr.evaluate(intptr)
+		if err != nil {
+			return Value{}, err
+		}
+		vals = append(vals, val)
 	}
+	return arrayValue(vals), nil
+}
 
-	if tipe := reflect.TypeOf(object); tipe != reflect.TypeOf(JlangClassInstance{}) {
-		return nil, BadMethodInvocation{method.identifier, fmt.Errorf("type '%s' does not implement method invocation.", tipe.String())}
+func (m MapLiteral) evaluate(intptr *Interpreter) (Value, error) {
+	vals := newJlangMap()
+	for i, keyExpr := range m.keys {
+		key, err := keyExpr.evaluate(intptr)
+		if err != nil {
+			return Value{}, err
+		}
+		if err := checkKey(key); err != nil {
+			return Value{}, BadIndex{spanOf(keyExpr), err}
+		}
+		val, err := m.values[i].evaluate(intptr)
+		if err != nil {
+			return Value{}, err
+		}
+		vals.set(key, val)
 	}
-
-	return object.(JlangClassInstance).invoke(intptr, FunctionCall{
-		identifier: method.identifier,
-		args:       method.argExprs,
-	})
+	return mapValue(vals), nil
 }
 
-func (prop PropertyAccess) evaluate(intptr *Interpreter) (Value, error) {
-	val, err := prop.Expr.evaluate(intptr)
+func (array ArrayAccess) evaluate(intptr *Interpreter) (Value, error) {
+	container, index, err := array.resolve(intptr)
 	if err != nil {
-		return nil, err
-	}
-	switch reflect.TypeOf(val).String() {
-	case reflect.TypeOf(JlangClassInstance{}).String():
-		return val.(JlangClassInstance).propertyAccess(prop.identifier)
-	case reflect.TypeOf(JlangClass{}).String():
-		return val.(JlangClass).evaluate(intptr)
+		return Value{}, err
 	}
+	return get(container, index), nil
+}
 
-	return nil, BadPropertyAccess{prop.identifier, fmt.Errorf("type '%s' does not implement property access", reflect.TypeOf(val))}
+// get is the element of an array or map at an index that was already checked
+func get(container Value, index Value) Value {
+	switch container.kind {
+	case MapKind:
+		return container.asMap().get(index)
+	case ArrayKind:
+		return container.asArray()[index.asInt()]
+	}
+	return Value{}
 }
 
-func (array ArrayAccess) evaluate(intptr *Interpreter) (Value, error) {
+// resolve evaluates the array or map being accessed and the index into it, making sure the index is valid
+// for the container i.e in bounds for an array
+func (array ArrayAccess) resolve(intptr *Interpreter) (Value, Value, error) {
+	container, err := array.Expr.evaluate(intptr)
+	if err != nil {
+		return Value{}, Value{}, err
+	}
 	index, err := array.index.evaluate(intptr)
 	if err != nil {
-		return nil, err
+		return Value{}, Value{}, err
 	}
-	if kind := reflect.TypeOf(index).Kind(); kind != reflect.Int {
-		return nil, fmt.Errorf("invalid type '%s' for index of array '%s'", kind.String(), array.identifier.Lexeme)
+	if err := array.check(container, index); err != nil {
+		return Value{}, Value{}, err
 	}
-	return intptr.env.arrayResolve(array, index.(int))
+	return container, index, nil
 }
 
-func getLeftRightKinds(left Value, right Value) (reflect.Kind, reflect.Kind) {
-	return reflect.TypeOf(left).Kind(), reflect.TypeOf(right).Kind()
+// check makes sure index is valid for the already evaluated container
+func (array ArrayAccess) check(container Value, index Value) error {
+	switch container.kind {
+	case MapKind:
+		if err := checkKey(index); err != nil {
+			return BadIndex{spanOf(array.index), err}
+		}
+	case ArrayKind:
+		if index.kind != IntKind {
+			return BadIndex{spanOf(array.index), fmt.Errorf("invalid type '%s' for index of array '%s'", index.kind, spanOf(array.Expr).Lexeme)}
+		}
+		if i, elems := index.asInt(), container.asArray(); i < 0 || i >= len(elems) {
+			return OutOfBounds{spanOf(array.Expr).Lexeme, i, len(elems), spanOf(array.index)}
+		}
+	default:
+		return BadIndex{spanOf(array), fmt.Errorf("type '%s' can't be indexed", container.kind)}
+	}
+	return nil
 }
 
-func equal(left Value, right Value) bool {
-	// This is kind of cool :^) let's see how long it can hold...
-	return left == right
+// evaluateArgs evaluates each argument expression of a call, in order, in the caller's scope
+func evaluateArgs(intptr *Interpreter, exprs *[]Expression) ([]Value, error) {
+	args := make([]Value, 0)
+	if exprs == nil {
+		return args, nil
+	}
+	for _, expr := range *exprs {
+		val, err := expr.evaluate(intptr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, val)
+	}
+	return args, nil
 }
 
 func truthy(val Value) bool {
-	return val == true
+	return val.truthy()
 }
@@ -0,0 +1,40 @@
+package lang_test
+
+import (
+	"fmt"
+	"github.com/jntun/mylang/lang"
+)
+
+// A lint pass written outside the package, it finds every function that's declared and never called by name.
+func ExampleInspect() {
+	program, err := lang.ParseSource(&lang.Source{Text: "" +
+		"func used() { return 1; }\n" +
+		"func unused() { return used(); }\n" +
+		"print used();\n",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	declared := make([]lang.Token, 0)
+	called := make(map[string]bool)
+	lang.Inspect(program, func(node lang.Node) bool {
+		switch n := node.(type) {
+		case lang.FunctionDeclarationStatement:
+			declared = append(declared, n.Identifier)
+		case lang.Call:
+			callee := lang.Fields(n)[0].Value
+			if variable, ok := callee.(lang.Variable); ok {
+				called[lang.Span(variable).Lexeme] = true
+			}
+		}
+		return true
+	})
+
+	for _, name := range declared {
+		if !called[name.Lexeme] {
+			fmt.Printf("%s: '%s' is never called\n", name.Position(), name.Lexeme)
+		}
+	}
+	// Output: 2:6: 'unused' is never called
+}
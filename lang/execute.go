@@ -1,8 +1,6 @@
 package lang
 
-import (
-	"reflect"
-)
+import ()
 
 func (program Program) execute(intptr *Interpreter) error {
 	for _, stmt := range program.Statements {
@@ -19,23 +17,22 @@ func (stmt PrintStatement) execute(intptr *Interpreter) error {
 	if err != nil {
 		return err
 	}
-	intptr.writeLog.Println(val)
+	intptr.writeLog.Println(val.String())
 	return nil
 }
 
 func (stmt VariableStatement) execute(intptr *Interpreter) error {
 	//stmt.resolver(stmt)
-	intptr.VariableMap(stmt)
-	return nil
+	return intptr.VariableMap(stmt)
 }
 
 func (stmt AssignmentStatement) execute(intptr *Interpreter) error {
-	_, err := Variable{stmt.VariableStatement.Identifier}.evaluate(intptr)
+	val, err := stmt.Expr.evaluate(intptr)
 	if err != nil {
 		return err
 	}
 
-	return stmt.VariableStatement.execute(intptr)
+	return intptr.env.varAssign(stmt.Identifier, stmt.local, val)
 }
 
 func (stmt PropertyAssignmentStatement) execute(intptr *Interpreter) error {
@@ -44,46 +41,37 @@ func (stmt PropertyAssignmentStatement) execute(intptr *Interpreter) error {
 		return err
 	}
 
-	// This is a switch so it can be expanded easily in the future
-	switch reflect.TypeOf(object).String() {
-	case reflect.TypeOf(JlangClassInstance{}).String():
-		break
-	default:
-		return BadPropertyAssignmentType{stmt.get.identifier, reflect.TypeOf(object).String()}
+	if err := stmt.check(object); err != nil {
+		return err
 	}
 
-	return object.(JlangClassInstance).updateMember(intptr, VariableStatement{
+	return object.asInstance().updateMember(intptr, VariableStatement{
 		Identifier: stmt.get.identifier,
 		Expr:       stmt.value,
 	})
 }
 
-func (stmt IfStatement) execute(intptr *Interpreter) error {
-	var exec []Statement
+// check makes sure the object can have its members assigned, before the value is evaluated
+func (stmt PropertyAssignmentStatement) check(object Value) error {
+	// This is a switch so it can be expanded easily in the future
+	switch object.kind {
+	case InstanceKind:
+		return nil
+	}
+	return BadPropertyAssignmentType{stmt.get.identifier, object.kind.String()}
+}
 
+func (stmt IfStatement) execute(intptr *Interpreter) error {
 	val, err := stmt.Expr.evaluate(intptr)
 	if err != nil {
 		return err
 	}
 
-	if ok := truthy(val); ok {
-		exec = stmt.block
-
+	if truthy(val) {
+		return intptr.executeBlock("if", stmt.block)
 	} else if stmt.elseBlock != nil {
-		exec = *stmt.elseBlock
+		return intptr.executeBlock("if", *stmt.elseBlock)
 	}
-
-	for _, stmt := range exec {
-		err = stmt.execute(intptr)
-		if err != nil {
-			return err
-		}
-
-		if intptr.shouldBreak() {
-			break
-		}
-	}
-
 	return nil
 }
 
@@ -92,19 +80,29 @@ func (stmt FunctionDeclarationStatement) execute(intptr *Interpreter) error {
 	return nil
 }
 
-func (stmt ArrayDeclarationStatement) execute(intptr *Interpreter) error {
-	vals := make([]*Value, 0)
-	for _, expr := range stmt.ExprList {
-		if val, err := expr.evaluate(intptr); err != nil {
-			return err
-		} else {
-			vals = append(vals, &val)
-		}
+func (stmt IndexAssignmentStatement) execute(intptr *Interpreter) error {
+	container, index, err := stmt.get.resolve(intptr)
+	if err != nil {
+		return err
+	}
+	val, err := stmt.value.evaluate(intptr)
+	if err != nil {
+		return err
 	}
-	intptr.env.arrayStore(stmt.Identifier.Lexeme, vals)
+	set(container, index, val)
 	return nil
 }
 
+// set stores val in an array or map at an index that was already checked
+func set(container Value, index Value, val Value) {
+	switch container.kind {
+	case MapKind:
+		container.asMap().set(index, val)
+	case ArrayKind:
+		container.asArray()[index.asInt()] = val
+	}
+}
+
 func (stmt ReturnStatement) execute(intptr *Interpreter) error {
 	val, err := stmt.Expression.evaluate(intptr)
 	if err != nil {
@@ -115,27 +113,19 @@ func (stmt ReturnStatement) execute(intptr *Interpreter) error {
 }
 
 func (stmt WhileStatement) execute(intptr *Interpreter) error {
-	val, err := stmt.test.evaluate(intptr)
-	if err != nil {
-		return err
-	}
-	for truthy(val) {
+	for {
+		val, err := stmt.test.evaluate(intptr)
 		if err != nil {
 			return err
 		}
-		for _, exec := range stmt.block {
-			err := exec.execute(intptr)
-			if err != nil {
-				return err
-			}
+		if !truthy(val) {
+			return nil
 		}
-		val, err = stmt.test.evaluate(intptr)
 
-		if intptr.shouldBreak() {
-			break
+		if stop, err := intptr.loopBody(stmt.block); err != nil || stop {
+			return err
 		}
 	}
-	return nil
 }
 
 func (stmt ForStatement) execute(intptr *Interpreter) error {
@@ -148,31 +138,89 @@ func (stmt ForStatement) execute(intptr *Interpreter) error {
 			return err
 		}
 	}
-	val, err := stmt.test.evaluate(intptr)
-	if err != nil {
-		return err
-	}
-	for truthy(val) {
+	for {
+		val, err := stmt.test.evaluate(intptr)
 		if err != nil {
 			return err
 		}
-		for _, exec := range stmt.block {
-			if err := exec.execute(intptr); err != nil {
-				return err
-			}
+		if !truthy(val) {
+			return nil
 		}
-		val, err = stmt.test.evaluate(intptr)
-		if truthy(val) {
-			if assignErr := stmt.assign.execute(intptr); assignErr != nil {
-				return assignErr
-			}
+
+		if stop, err := intptr.loopBody(stmt.block); err != nil || stop {
+			return err
+		}
+
+		// A 'continue' still steps the loop forward
+		if err := stmt.assign.execute(intptr); err != nil {
+			return err
 		}
+	}
+}
+
+func (stmt BreakStatement) execute(intptr *Interpreter) error {
+	intptr.loopJump = &stmt.keyword
+	return nil
+}
 
+func (stmt ContinueStatement) execute(intptr *Interpreter) error {
+	intptr.loopJump = &stmt.keyword
+	return nil
+}
+
+func (stmt ThrowStatement) execute(intptr *Interpreter) error {
+	val, err := stmt.Expression.evaluate(intptr)
+	if err != nil {
+		return err
+	}
+	return stmt.throw(val)
+}
+
+// throw is the error for throwing val. An Error that hasn't been thrown yet gets the line it's thrown from.
+func (stmt ThrowStatement) throw(val Value) error {
+	if val.kind == InstanceKind {
+		instance := val.asInstance()
+		if line, found := instance.member("line"); found && line.isNil() {
+			instance.scope.varStore("line", intValue(int(stmt.keyword.Line)))
+		}
+	}
+	return Thrown{stmt.keyword, val}
+}
+
+func (stmt TryStatement) execute(intptr *Interpreter) error {
+	err := intptr.executeBlock("try", stmt.block)
+	if err != nil && stmt.catchBlock != nil && catchable(err) {
+		err = stmt.catch(intptr, err)
+	}
+	if stmt.finallyBlock == nil {
+		return err
+	}
+
+	// Whatever the try or catch finished with, an error, a return or a 'break', carries on after the finally.
+	// Unless the finally finishes with one of its own, which replaces it.
+	ret, jump, failed := intptr.funcRet, intptr.loopJump, intptr.failed
+	intptr.funcRet, intptr.loopJump, intptr.failed = nil, nil, nil
+	if finallyErr := intptr.executeBlock("finally", *stmt.finallyBlock); finallyErr != nil || intptr.shouldBreak() {
+		return finallyErr
+	}
+	intptr.funcRet, intptr.loopJump, intptr.failed = ret, jump, failed
+	return err
+}
+
+// catch runs the catch block in a block of its own with the caught error declared in it
+func (stmt TryStatement) catch(intptr *Interpreter, err error) error {
+	intptr.env.push("catch")
+	defer intptr.env.pop()
+
+	intptr.env.varStore(stmt.catchParam.Lexeme, intptr.caught(err))
+	for _, stmt := range *stmt.catchBlock {
+		if err := stmt.execute(intptr); err != nil {
+			return err
+		}
 		if intptr.shouldBreak() {
 			break
 		}
 	}
-
 	return nil
 }
 
@@ -183,3 +231,15 @@ func (stmt ExpressionStatement) execute(intptr *Interpreter) error {
 	}
 	return nil
 }
+
+// declares is whether a block declares anything directly in it. A block that doesn't isn't given a scope
+// of its own, by the Resolver or when it runs.
+func declares(block []Statement) bool {
+	for _, stmt := range block {
+		switch stmt.(type) {
+		case VariableStatement, FunctionDeclarationStatement, JlangClass:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,480 @@
+package lang
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Format is the canonical formatting of a jlang source, the same way gofmt is for go source.
+// Statements are one per line and indented 4 spaces a block, with single spaces between tokens.
+// Comments stay where they were relative to the statements around them, and single blank lines are kept.
+func Format(source *Source) (string, error) {
+	scan := Scanner{}
+	tokens, err := scan.ScanSource(source)
+	if err != nil {
+		return "", ScanError{err}
+	}
+	tokens = append(tokens, scan.eof())
+
+	p := Parser{}
+	program, err := p.Parse(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	f := newFormatter(source.Text, tokens)
+	f.statements(program.Statements, uint(len(source.Text))+1)
+	return f.out.String(), nil
+}
+
+// formatter pretty prints a Program. The AST doesn't keep every token so it looks back at the
+// scanned tokens to place comments, blank lines and the '}' of blocks.
+type formatter struct {
+	src      string
+	tokens   []Token
+	comments []comment // Every comment in the source, in order
+	next     int       // The next comment to write
+	lastEnd  uint      // Where the last written comment ended
+	indent   int
+	out      bytes.Buffer
+}
+
+type comment struct {
+	Trivia
+	trailing bool
+	written  bool // Already written inline, i.e after a parameter
+}
+
+func newFormatter(src string, tokens []Token) *formatter {
+	f := &formatter{src: src, tokens: tokens}
+	for _, token := range tokens {
+		for _, trivia := range token.Leading {
+			f.comments = append(f.comments, comment{trivia, false, false})
+		}
+		for _, trivia := range token.Trailing {
+			f.comments = append(f.comments, comment{trivia, true, false})
+		}
+	}
+	return f
+}
+
+func (f *formatter) write(str string) {
+	f.out.WriteString(str)
+}
+
+func (f *formatter) writeIndent() {
+	f.write(strings.Repeat("    ", f.indent))
+}
+
+// statements writes each statement on its own line, then the comments that come before end
+func (f *formatter) statements(stmts []Statement, end uint) {
+	for _, stmt := range stmts {
+		start := f.first(stmt).Start
+		f.flushComments(start)
+		f.separate(start)
+		f.writeIndent()
+		f.statement(stmt)
+		f.write("\n")
+	}
+	f.flushComments(end)
+}
+
+// flushComments writes every comment that starts before offset. Comments that trailed a token go at
+// the end of the last line, and the rest go on their own line.
+func (f *formatter) flushComments(offset uint) {
+	for ; f.next < len(f.comments) && f.comments[f.next].Start < offset; f.next++ {
+		comment := f.comments[f.next]
+		if comment.written {
+			continue
+		}
+		if out := f.out.Bytes(); comment.trailing && len(out) > 0 && out[len(out)-1] == '\n' {
+			f.out.Truncate(len(out) - 1)
+			f.write(" " + comment.Text + "\n")
+		} else {
+			f.separate(comment.Start)
+			f.writeIndent()
+			f.write(comment.Text + "\n")
+		}
+		f.lastEnd = comment.End
+	}
+}
+
+// separate writes a blank line if there was at least one in the source before offset.
+// There's never more than one, and never one at the start of a block.
+func (f *formatter) separate(offset uint) {
+	out := f.out.Bytes()
+	if len(out) == 0 || bytes.HasSuffix(out, []byte("{\n")) || bytes.HasSuffix(out, []byte("\n\n")) {
+		return
+	}
+	from := f.lastEnd
+	if i := f.index(offset); i > 0 && f.tokens[i-1].End > from {
+		from = f.tokens[i-1].End
+	}
+	if from <= offset && strings.Count(f.src[from:offset], "\n") > 1 {
+		f.write("\n")
+	}
+}
+
+// index is the index of the first token that starts at or after offset
+func (f *formatter) index(offset uint) int {
+	return sort.Search(len(f.tokens), func(i int) bool {
+		return f.tokens[i].Start >= offset
+	})
+}
+
+// before is the token n tokens before the one at offset
+func (f *formatter) before(offset uint, n int) Token {
+	if i := f.index(offset) - n; i >= 0 {
+		return f.tokens[i]
+	}
+	return f.tokens[0]
+}
+
+// first is the first token of a statement, which is the keyword of statements that have one
+func (f *formatter) first(stmt Statement) Token {
+	switch s := stmt.(type) {
+	case VariableStatement:
+		return f.before(s.Identifier.Start, 1)
+	case FunctionDeclarationStatement:
+		return f.before(s.Identifier.Start, 1)
+	case JlangClass:
+		return f.before(s.identifier.Start, 1)
+	case AssignmentStatement:
+		return s.Identifier
+	case PropertyAssignmentStatement:
+		return spanOf(s.get)
+	case IndexAssignmentStatement:
+		return spanOf(s.get)
+	case ExpressionStatement:
+		return spanOf(s.Expression)
+	case PrintStatement:
+		return f.before(spanOf(s.Expression).Start, 1)
+	case ReturnStatement:
+		if isBareReturn(s) {
+			return s.Expression.(Literal).Token
+		}
+		return f.before(spanOf(s.Expression).Start, 1)
+	case IfStatement:
+		return f.before(spanOf(s.Expr).Start, 1)
+	case WhileStatement:
+		return f.before(spanOf(s.test).Start, 1)
+	case ForStatement:
+		if s.varStmt != nil {
+			return f.before(s.varStmt.Identifier.Start, 2)
+		}
+		return f.before(spanOf(s.test).Start, 1)
+	case BreakStatement:
+		return s.keyword
+	case ContinueStatement:
+		return s.keyword
+	case ThrowStatement:
+		return s.keyword
+	case TryStatement:
+		return s.keyword
+	}
+	return Token{}
+}
+
+// closing is the '}' of the first block that's opened at or after offset
+func (f *formatter) closing(offset uint) Token {
+	depth := 0
+	for i := f.index(offset); i < len(f.tokens); i++ {
+		switch f.tokens[i].Type {
+		case LeftBrace:
+			depth++
+		case RightBrace:
+			if depth--; depth == 0 {
+				return f.tokens[i]
+			}
+		}
+	}
+	return f.tokens[len(f.tokens)-1]
+}
+
+// block writes the statements of the first block opened at or after offset, and returns its '}'
+func (f *formatter) block(stmts []Statement, offset uint) Token {
+	closing := f.closing(offset)
+	if len(stmts) == 0 && (f.next >= len(f.comments) || f.comments[f.next].Start > closing.Start) {
+		f.write("{}")
+		return closing
+	}
+
+	f.write("{\n")
+	f.indent++
+	f.statements(stmts, closing.Start)
+	f.indent--
+	f.writeIndent()
+	f.write("}")
+	return closing
+}
+
+func (f *formatter) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case VariableStatement:
+		f.variable(s)
+		f.write(";")
+	case AssignmentStatement:
+		f.write(s.Identifier.Lexeme + " = ")
+		f.expression(s.Expr)
+		f.write(";")
+	case PropertyAssignmentStatement:
+		f.expression(s.get)
+		f.write(" = ")
+		f.expression(s.value)
+		f.write(";")
+	case IndexAssignmentStatement:
+		f.expression(s.get)
+		f.write(" = ")
+		f.expression(s.value)
+		f.write(";")
+	case ExpressionStatement:
+		f.expression(s.Expression)
+		f.write(";")
+	case PrintStatement:
+		f.write("print")
+		// 'print(x)' stays as is
+		if _, ok := s.Expression.(Grouping); !ok {
+			f.write(" ")
+		}
+		f.expression(s.Expression)
+		f.write(";")
+	case ReturnStatement:
+		if isBareReturn(s) {
+			f.write("return;")
+			break
+		}
+		f.write("return ")
+		f.expression(s.Expression)
+		f.write(";")
+	case BreakStatement:
+		f.write("break;")
+	case ContinueStatement:
+		f.write("continue;")
+	case ThrowStatement:
+		f.write("throw ")
+		f.expression(s.Expression)
+		f.write(";")
+	case IfStatement:
+		f.ifStatement(s)
+	case TryStatement:
+		f.write("try ")
+		closing := f.block(s.block, s.keyword.End)
+		if s.catchBlock != nil {
+			f.write(" catch (" + s.catchParam.Lexeme + ") ")
+			closing = f.block(*s.catchBlock, s.catchParam.End)
+		}
+		if s.finallyBlock != nil {
+			f.write(" finally ")
+			f.block(*s.finallyBlock, closing.End)
+		}
+	case WhileStatement:
+		f.write("while ")
+		f.expression(s.test)
+		f.write(" ")
+		f.block(s.block, spanOf(s.test).End)
+	case ForStatement:
+		f.write("for ")
+		if s.varStmt != nil {
+			f.variable(*s.varStmt)
+			f.write("; ")
+		}
+		f.expression(s.test)
+		f.write("; " + s.assign.Identifier.Lexeme + " = ")
+		f.expression(s.assign.Expr)
+		f.write(" ")
+		f.block(s.block, spanOf(s.assign.Expr).End)
+	case FunctionDeclarationStatement:
+		f.write("func " + s.Identifier.Lexeme + "(" + f.parameters(s) + ") ")
+		f.block(s.block, s.Identifier.End)
+	case JlangClass:
+		f.class(s)
+	}
+}
+
+func (f *formatter) variable(stmt VariableStatement) {
+	f.write("var " + stmt.Identifier.Lexeme)
+	if stmt.Expr != nil {
+		f.write(" = ")
+		f.expression(stmt.Expr)
+	}
+}
+
+func (f *formatter) ifStatement(stmt IfStatement) {
+	f.write("if ")
+	f.expression(stmt.Expr)
+	f.write(" ")
+	closing := f.block(stmt.block, spanOf(stmt.Expr).End)
+	if stmt.elseBlock == nil {
+		return
+	}
+
+	f.write(" else ")
+	// The token after the 'else' is whether it was an 'else if' or an else block that happens to only have an if statement
+	if i := f.index(closing.End) + 1; i < len(f.tokens) && f.tokens[i].is(If) {
+		f.ifStatement((*stmt.elseBlock)[0].(IfStatement))
+		return
+	}
+	f.block(*stmt.elseBlock, closing.End)
+}
+
+// class writes the members of a class in the order they were declared
+func (f *formatter) class(class JlangClass) {
+	f.write("class " + class.identifier.Lexeme)
+	if class.Stmt.superclass != nil {
+		// Either '<' or 'extends'
+		keyword := f.tokens[f.index(class.identifier.End)]
+		f.write(" " + keyword.Lexeme + " " + class.Stmt.superclass.identifier.Lexeme)
+	}
+	f.write(" ")
+
+	members := make([]Statement, 0)
+	for _, member := range classMembers(class) {
+		members = append(members, member.(Statement))
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return f.first(members[i]).Start < f.first(members[j]).Start
+	})
+	f.block(members, class.identifier.End)
+}
+
+func (f *formatter) expression(expr Expression) {
+	switch e := expr.(type) {
+	case Binary:
+		f.expression(e.Left)
+		f.write(" " + e.Op.Lexeme + " ")
+		f.expression(e.Right)
+	case Logical:
+		f.expression(e.Left)
+		f.write(" " + e.Op.Lexeme + " ")
+		f.expression(e.Right)
+	case Grouping:
+		f.write("(")
+		f.expression(e.Expr)
+		f.write(")")
+	case Unary:
+		f.write(e.Op.Lexeme)
+		// '- -x' can't become '--x'
+		if inner, ok := e.Expr.(Unary); ok && inner.Op.Lexeme[0] == e.Op.Lexeme[len(e.Op.Lexeme)-1] {
+			f.write(" ")
+		}
+		f.expression(e.Expr)
+	case Literal:
+		f.write(f.source(e.Token))
+	case Interpolation:
+		f.write(f.source(spanOf(e)))
+	case Variable:
+		f.write(e.identifier.Lexeme)
+	case Call:
+		f.expression(e.callee)
+		f.arguments("(", e.args, ")")
+	case Lambda:
+		f.lambda(e)
+	case SuperAccess:
+		f.write("super")
+		if e.method != nil {
+			f.write("." + e.method.Lexeme)
+		}
+	case MethodInvocation:
+		f.expression(e.this)
+		f.write("." + e.identifier.Lexeme)
+		f.arguments("(", e.argExprs, ")")
+	case PropertyAccess:
+		f.expression(e.Expr)
+		f.write("." + e.identifier.Lexeme)
+	case ArrayAccess:
+		f.expression(e.Expr)
+		f.write("[")
+		f.expression(e.index)
+		f.write("]")
+	case ArrayLiteral:
+		f.arguments("[", &e.elements, "]")
+	case MapLiteral:
+		f.write("{")
+		for i, key := range e.keys {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.expression(key)
+			f.write(": ")
+			f.expression(e.values[i])
+		}
+		f.write("}")
+	}
+}
+
+func (f *formatter) arguments(open string, exprs *[]Expression, close string) {
+	f.write(open)
+	if exprs != nil {
+		for i, expr := range *exprs {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.expression(expr)
+		}
+	}
+	f.write(close)
+}
+
+func (f *formatter) lambda(lambda Lambda) {
+	decl := lambda.decl
+	if !decl.Identifier.is(Arrow) {
+		f.write("func(" + f.parameters(decl) + ") ")
+		f.block(decl.block, decl.Identifier.End)
+		return
+	}
+
+	// 'x => x' keeps its single parameter without parentheses
+	if lambda.start.is(Identifier) {
+		f.write(f.parameters(decl) + " => ")
+	} else {
+		f.write("(" + f.parameters(decl) + ") => ")
+	}
+	if f.tokens[f.index(decl.Identifier.End)].is(LeftBrace) {
+		f.block(decl.block, decl.Identifier.End)
+		return
+	}
+	f.expression(decl.block[0].(ReturnStatement).Expression)
+}
+
+// source is the text a token was scanned from, which is what strings are written as so their quotes and escapes are kept
+func (f *formatter) source(token Token) string {
+	if token.src == nil || token.End > uint(len(f.src)) {
+		return token.Lexeme
+	}
+	return f.src[token.Start:token.End]
+}
+
+// parameters is the parameter list of a function as it's written. A block comment after a parameter stays
+// with it i.e 'func f(a /* x */, b)', instead of going to the end of the line.
+func (f *formatter) parameters(decl FunctionDeclarationStatement) string {
+	params := make([]string, 0)
+	for _, param := range parameterTokens(decl) {
+		text := param.Lexeme
+		for _, trivia := range param.Trailing {
+			if trivia.Block && !strings.ContainsRune(trivia.Text, '\n') {
+				text += " " + trivia.Text
+				f.inline(trivia)
+			}
+		}
+		params = append(params, text)
+	}
+	return strings.Join(params, ", ")
+}
+
+// inline marks a comment as written so flushComments doesn't write it again
+func (f *formatter) inline(trivia Trivia) {
+	i := sort.Search(len(f.comments), func(i int) bool {
+		return f.comments[i].Start >= trivia.Start
+	})
+	if i < len(f.comments) && f.comments[i].Start == trivia.Start {
+		f.comments[i].written = true
+		f.lastEnd = trivia.End
+	}
+}
+
+// isBareReturn is whether the statement was just 'return;', which the parser gives a nil Literal
+func isBareReturn(stmt ReturnStatement) bool {
+	literal, ok := stmt.Expression.(Literal)
+	return ok && literal.is(Nil) && literal.Lexeme == "retnil"
+}
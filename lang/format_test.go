@@ -0,0 +1,148 @@
+package lang
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	input := "" +
+		"var  x=1+2 ;\n" +
+		"func f( a,b ){ if a>b { return a; } else if a==b {return;} else{ return b; } }\n" +
+		"\n\n\n" +
+		"class A extends B { func A(x) { super(x); } var y; }\n" +
+		"for var i=0;i<3;i=++i{print(i);}\n" +
+		"var g = (a)=>a*2; var h = x => { return - -x; };\n" +
+		"var m = {\"a\":[1,2], `raw\\n`: 0x1F};\n" +
+		"while true{}\n" +
+		"try{throw Error(\"x\") ;}catch( e ){print e;}finally{}\n"
+	expected := "" +
+		"var x = 1 + 2;\n" +
+		"func f(a, b) {\n" +
+		"    if a > b {\n" +
+		"        return a;\n" +
+		"    } else if a == b {\n" +
+		"        return;\n" +
+		"    } else {\n" +
+		"        return b;\n" +
+		"    }\n" +
+		"}\n" +
+		"\n" +
+		"class A extends B {\n" +
+		"    func A(x) {\n" +
+		"        super(x);\n" +
+		"    }\n" +
+		"    var y;\n" +
+		"}\n" +
+		"for var i = 0; i < 3; i = ++i {\n" +
+		"    print(i);\n" +
+		"}\n" +
+		"var g = (a) => a * 2;\n" +
+		"var h = x => {\n" +
+		"    return - -x;\n" +
+		"};\n" +
+		"var m = {\"a\": [1, 2], `raw\\n`: 0x1F};\n" +
+		"while true {}\n" +
+		"try {\n" +
+		"    throw Error(\"x\");\n" +
+		"} catch (e) {\n" +
+		"    print e;\n" +
+		"} finally {}\n"
+
+	formatted, err := Format(&Source{Text: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != expected {
+		t.Errorf("formatted source did not match, got:\n%s", formatted)
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	input := "" +
+		"// Leading comment.\n" +
+		"var x = 1; // trailing\n" +
+		"\n" +
+		"/* Block\n" +
+		"   comment */\n" +
+		"func f() {\n" +
+		"    // Only a comment.\n" +
+		"}\n" +
+		"// At the end"
+
+	expected := input + "\n"
+	formatted, err := Format(&Source{Text: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != expected {
+		t.Errorf("formatted source did not match, got:\n%s", formatted)
+	}
+}
+
+func TestFormatParameterComments(t *testing.T) {
+	input := "" +
+		"func f(a /* x */, b) {\n" +
+		"    return a;\n" +
+		"}\n" +
+		"var g = func(a, b /* y */) {\n" +
+		"    return b;\n" +
+		"};\n" +
+		"var h = (a /* x */) => a;\n"
+
+	// The comments stay after their parameters, and formatting again doesn't move them
+	formatted, err := Format(&Source{Text: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != input {
+		t.Errorf("formatted source did not match, got:\n%s", formatted)
+	}
+	if again, err := Format(&Source{Text: formatted}); err != nil || again != formatted {
+		t.Errorf("formatting the formatted source changed it again:\n%s", again)
+	}
+}
+
+// TestFormatTests formats every script in /tests/, the formatted script has to be formatted already and run the same
+func TestFormatTests(t *testing.T) {
+	files, err := filepath.Glob("../tests/*.jlang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Printed instances show their addresses and locals() prints in map order, both of which change run to run
+	addresses := regexp.MustCompile("0x[0-9a-f]+")
+	run := func(text string) string {
+		out := strings.Builder{}
+		intptr := NewInterpreter()
+		intptr.HookLogOut(&out)
+		if err := intptr.Interpret(text); err != nil {
+			out.WriteString(err.Error())
+		}
+		lines := strings.Split(addresses.ReplaceAllString(out.String(), "0x"), "\n")
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	}
+
+	for _, file := range files {
+		text, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := Format(&Source{Text: string(text)})
+		if err != nil {
+			// Some of the scripts are there to fail
+			continue
+		}
+
+		if again, err := Format(&Source{Text: formatted}); err != nil || again != formatted {
+			t.Errorf("%s: formatting the formatted script changed it again:\n%s", file, again)
+		}
+		if run(string(text)) != run(formatted) {
+			t.Errorf("%s: formatted script didn't run the same", file)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package lang
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Callable is a Value that can be called with arguments i.e 'f(1, 2)'.
+type Callable interface {
+	// signature is the Token the callable was declared with and the amount of arguments it takes
+	signature() (Token, uint)
+	call(intptr *Interpreter, site Token, args []Value) (Value, error)
+}
+
+// JlangFunction is a function value. It closes over the Environment it was declared in so it keeps
+// seeing the same variables wherever it gets stored, passed or called from.
+type JlangFunction struct {
+	decl    FunctionDeclarationStatement
+	closure Environment
+	this    *JlangClassInstance // The instance a method is bound to, which is passed as its first argument
+	code    *chunk              // The compiled body when the function was declared by the VM
+}
+
+func (fun JlangFunction) signature() (Token, uint) {
+	return fun.decl.Identifier, fun.decl.arity
+}
+
+// call executes the function body in a new block on top of its closure. It's assumed the
+// amount of args has already been checked against signature() i.e by Interpreter.callValue()
+func (fun JlangFunction) call(intptr *Interpreter, site Token, args []Value) (val Value, err error) {
+	if err = intptr.enter(fun, site); err != nil {
+		return Value{}, err
+	}
+	defer func() {
+		intptr.leave(err)
+	}()
+
+	if fun.code != nil {
+		return fun.run(intptr, args)
+	}
+	intptr.env = fun.closure
+	intptr.env.push(fun.decl.Identifier.Lexeme)
+	if fun.decl.args != nil {
+		params := *fun.decl.args
+		if fun.this != nil {
+			intptr.env.varStore(params[0].Lexeme, instanceValue(*fun.this))
+			params = params[1:]
+		}
+		for i, param := range params {
+			intptr.env.varStore(param.Lexeme, args[i])
+		}
+	}
+
+	for _, stmt := range fun.decl.block {
+		if err := stmt.execute(intptr); err != nil {
+			return Value{}, err
+		}
+		if intptr.shouldBreak() {
+			break
+		}
+	}
+
+	if intptr.funcRet != nil {
+		val := *intptr.funcRet
+		intptr.funcRet = nil
+		return val, nil
+	}
+	return Value{}, nil
+}
+
+// run runs the compiled body in a block with a slot for each of its variables. The block is kept for the next call
+// unless the body makes a closure or class, which could still see it after the call.
+func (fun JlangFunction) run(intptr *Interpreter, args []Value) (Value, error) {
+	code := fun.code
+	var frame *varMap
+	if n := len(code.frames); n > 0 {
+		frame, code.frames = code.frames[n-1], code.frames[:n-1]
+	} else {
+		frame = newFrame(code.names)
+	}
+	if fun.this != nil {
+		frame.values = append(frame.values, instanceValue(*fun.this))
+	}
+	frame.values = append(frame.values, args...)
+
+	// The call's Environment is the closure and its own block, on top of the ones of the calls it's in
+	base := len(intptr.blocks)
+	intptr.blocks = append(append(intptr.blocks, fun.closure.vars...), Block{fun.decl.Identifier.Lexeme, frame})
+	intptr.env = Environment{intptr.blocks[base:len(intptr.blocks):len(intptr.blocks)]}
+	val, err := intptr.run(code, base)
+	for i := base; i < len(intptr.blocks); i++ {
+		intptr.blocks[i] = Block{}
+	}
+	intptr.blocks = intptr.blocks[:base]
+	if !code.captures {
+		frame.reset(code.names)
+		code.frames = append(code.frames, frame)
+	}
+	return val, err
+}
+
+// same is whether both are the same function declared in the same scope, and bound to the same instance if either is
+func (fun JlangFunction) same(other JlangFunction) bool {
+	l, r := fun.decl.Identifier, other.decl.Identifier
+	if l.Lexeme != r.Lexeme || l.Line != r.Line || l.Start != r.Start || (fun.this == nil) != (other.this == nil) {
+		return false
+	}
+	if fun.this != nil && !fun.this.same(*other.this) {
+		return false
+	}
+	lvars, rvars := fun.closure.vars, other.closure.vars
+	return len(lvars) == len(rvars) && (len(lvars) == 0 || lvars[len(lvars)-1].store == rvars[len(rvars)-1].store)
+}
+
+// bind makes the function a method of instance
+func (fun JlangFunction) bind(instance JlangClassInstance) JlangFunction {
+	fun.this = &instance
+	return fun
+}
+
+func (fun JlangFunction) String() string {
+	params := make([]string, 0)
+	if fun.decl.args != nil {
+		for _, param := range *fun.decl.args {
+			params = append(params, param.Lexeme)
+		}
+	}
+	if fun.this != nil {
+		params = params[1:]
+	}
+	if fun.anonymous() {
+		return fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	}
+	return fmt.Sprintf("func %s(%s)", fun.decl.Identifier.Lexeme, strings.Join(params, ", "))
+}
+
+// name is what the function is called in a Traceback i.e 'area', 'Rect#area' or 'func' when it doesn't have one
+func (fun JlangFunction) name() string {
+	name := fun.decl.Identifier.Lexeme
+	if fun.anonymous() {
+		name = "func"
+	}
+	if fun.this != nil {
+		return fun.this.parent.identifier.Lexeme + "#" + name
+	}
+	return name
+}
+
+// anonymous is whether the function came from a Lambda, whose identifier is the 'func' or '=>' token
+func (fun JlangFunction) anonymous() bool {
+	return !fun.decl.Identifier.is(Identifier)
+}
@@ -11,41 +11,87 @@ import (
 type Interpreter struct {
 	s        *Scanner
 	p        *Parser
+	r        *Resolver
 	env      Environment
+	calls    []call  // Each call in progress, innermost last
+	failed   []Frame // The calls an error was raised in, innermost first, until it's caught or returned
 	funcRet  *Value
+	loopJump *Token // The 'break' or 'continue' keyword until the loop it's in handles it
 	writeLog *log.Logger
+	warnLog  *log.Logger
+	vm       bool        // Whether programs are compiled and run by the VM instead of walked
+	maxDepth int         // How many calls deep a program can go before it's a StackOverflow
+	errors   *JlangClass // The builtin Error class, which errors that weren't thrown are caught as
+
+	// The VM reuses these between calls so calling compiled code doesn't allocate
+	blocks  []Block   // The Environments of the compiled calls in progress are each a slice of these
+	stacks  []vmStack // The operand stacks of runs that have returned
+	varMaps []*varMap // Blocks compiled code has popped that nothing can see anymore
+}
+
+// Option configures an Interpreter made by NewInterpreter
+type Option func(intptr *Interpreter)
+
+// DefaultMaxDepth is how many calls deep a program can go unless WithMaxDepth says otherwise
+const DefaultMaxDepth = 1000
+
+// WithMaxDepth sets how many calls deep a program can go before the call that goes deeper is a StackOverflow.
+// Each call takes up some of the Go stack, so a very deep one can still crash the process.
+func WithMaxDepth(depth int) Option {
+	return func(intptr *Interpreter) {
+		intptr.maxDepth = depth
+	}
+}
+
+// WithVM compiles programs to bytecode and runs them on a stack VM instead of walking their AST.
+// Both run programs the same way.
+func WithVM() Option {
+	return func(intptr *Interpreter) {
+		intptr.vm = true
+	}
 }
 
 // Interpret accepts an input string and attempts to execute the given sequence
 /* If a fatal error is encountered at any point, the Interpreter will break out and return an error
    describing the problem */
 func (intptr *Interpreter) Interpret(input string) error {
-	tokens, err := intptr.s.Scan(input)
+	return intptr.interpretSource(&Source{Text: input})
+}
+
+func (intptr *Interpreter) interpretSource(source *Source) error {
+	tokens, err := intptr.s.ScanSource(source)
 	if err != nil {
 		return ScanError{err}
 	}
 
-	ast, err := intptr.p.Parse(append(tokens, Token{"EOF", EOF, tokens[len(tokens)-1].Line}))
+	ast, err := intptr.p.Parse(append(tokens, intptr.s.eof()))
 	if err != nil {
 		return err
 	}
 
-	if len(intptr.p.Errors) > 1 {
-		errList := make([]error, 0)
-		for _, err2 := range intptr.p.Errors {
-			errList = append(errList, err2)
-		}
-	}
-
-	err = intptr.interpret(*ast)
-	if err != nil {
+	if err = intptr.r.Resolve(ast, intptr.env.vars[0].store.names); err != nil {
 		return err
 	}
+	for _, warning := range intptr.r.Warnings {
+		intptr.warnLog.Println("Warning: " + warning.Error())
+	}
 
-	return nil
+	intptr.failed = nil
+	if err = intptr.interpret(*ast); err != nil && len(intptr.failed) > 0 {
+		err = Traceback{err, intptr.failed}
+	}
+	return err
 }
 
 func (intptr *Interpreter) interpret(program Program) error {
+	if intptr.vm {
+		code, err := compile(&program)
+		if err != nil {
+			return err
+		}
+		_, err = intptr.run(code, -1)
+		return err
+	}
 	return program.execute(intptr)
 }
 
@@ -53,18 +99,18 @@ func (intptr *Interpreter) interpret(program Program) error {
 // This allows the interpreter to handle state and higher-order operations.
 // It is assumed that when called, the Scanner has already determined it to be a lexically _valid_
 // variable statement and now it's up to the interpreter to breathe life into it.
-func (intptr *Interpreter) VariableMap(stmt VariableStatement) {
+func (intptr *Interpreter) VariableMap(stmt VariableStatement) error {
 	if stmt.Expr == nil {
-		intptr.env.varStore(stmt.Identifier.Lexeme, nil)
-		return
+		intptr.env.varStore(stmt.Identifier.Lexeme, Value{})
+		return nil
 	}
 	val, err := stmt.Expr.evaluate(intptr)
 	if err != nil {
-		intptr.writeLog.Printf("%s\n", fmt.Errorf("invalid assignment expression on %d at '%s': %s", stmt.Identifier.Line, stmt.Identifier.Lexeme, err))
-		return
+		return err
 	}
 
-	intptr.env.varStore(stmt.Identifier.Lexeme, &val)
+	intptr.env.varStore(stmt.Identifier.Lexeme, val)
+	return nil
 }
 
 // VariableResolver is how an Identifier gets resolved to a real Value.
@@ -75,22 +121,67 @@ func (intptr *Interpreter) VariableResolver(variable Variable) (Value, error) {
 
 // FunctionMap assumes the parser has *correctly* parsed a
 // FunctionDeclarationStatement and is now ready to be breathed life into from the interpreter.
+// The function becomes a Value closing over the current scope.
 func (intptr *Interpreter) FunctionMap(stmt FunctionDeclarationStatement) {
-	intptr.env.funcStore(FunctionInvocation{stmt, nil, 0})
+	fun := functionValue(JlangFunction{stmt, intptr.env.capture(), nil, nil})
+	intptr.env.varStore(stmt.Identifier.Lexeme, fun)
 }
 
-func (intptr *Interpreter) FunctionResolve(caller FunctionCall) (Value, error) {
-	if fun, found := intptr.env.funcResolve(caller); found == true {
-		fun.FillArgs(caller.args)
-		fun.arity = uint(len(*caller.args))
+// call is a call in progress, and the environment it was made from. Its Frame is only made when there's
+// a Traceback, since naming a method after its class takes building a string.
+type call struct {
+	fun    JlangFunction
+	site   Token
+	caller Environment
+}
 
-		val, err := fun.evaluate(intptr)
-		if err != nil {
-			return nil, err
+func (c call) frame() Frame {
+	return Frame{c.fun.name(), c.site}
+}
+
+// enter pushes a call of fun that's made from the current environment, unless it's one call too deep. It's popped
+// by leave, which takes the trace of err if it's the first call err escapes from.
+func (intptr *Interpreter) enter(fun JlangFunction, site Token) error {
+	if len(intptr.calls) >= intptr.maxDepth {
+		frames := intptr.frames()
+		if len(frames) > 0 {
+			frames = frames[:cycle(traceLines(frames, site))]
 		}
-		return val, nil
+		return StackOverflow{site, intptr.maxDepth, frames}
+	}
+	intptr.calls = append(intptr.calls, call{fun, site, intptr.env})
+	return nil
+}
+
+func (intptr *Interpreter) leave(err error) {
+	if err != nil && intptr.failed == nil {
+		intptr.failed = intptr.frames()
+	}
+	intptr.env = intptr.calls[len(intptr.calls)-1].caller
+	intptr.calls = intptr.calls[:len(intptr.calls)-1]
+}
+
+// frames is the calls in progress, innermost first
+func (intptr *Interpreter) frames() []Frame {
+	frames := make([]Frame, len(intptr.calls))
+	for i, call := range intptr.calls {
+		frames[len(intptr.calls)-1-i] = call.frame()
 	}
-	return nil, BadCall{caller.identifier, nil}
+	return frames
+}
+
+// callValue calls callee if it is Callable with the right amount of arguments.
+// site is where the call was made from, used for errors and naming the call's block.
+func (intptr *Interpreter) callValue(callee Value, site Token, args []Value) (Value, error) {
+	fun, ok := callee.callable()
+	if !ok {
+		return Value{}, BadCall{site, fmt.Errorf("type '%s' is not callable", callee.kind)}
+	}
+	if identifier, arity := fun.signature(); arity != uint(len(args)) {
+		return Value{}, BadCall{site, ArgumentMismatch{identifier, arity, uint(len(args))}}
+	}
+
+	return fun.call(intptr, site, args)
 }
 
 func (intptr *Interpreter) FunctionReturn(val Value) {
@@ -105,7 +196,7 @@ func (intptr *Interpreter) File(filepath string) error {
 		return err
 	}
 
-	if err = intptr.Interpret(*src); err != nil {
+	if err = intptr.interpretSource(&Source{filepath, *src}); err != nil {
 		return err
 	}
 
@@ -117,30 +208,104 @@ func (intptr *Interpreter) HookLogOut(out io.Writer) error {
 	return nil
 }
 
+// HookWarnOut is where warnings about a program are written before it runs, which is stderr by default
+func (intptr *Interpreter) HookWarnOut(out io.Writer) error {
+	intptr.warnLog = log.New(out, "", 0)
+	return nil
+}
+
 func (intptr *Interpreter) shouldBreak() bool {
-	if intptr.funcRet != nil {
+	if intptr.funcRet != nil || intptr.loopJump != nil {
 		return true
 	}
 	return false
 }
 
+// loopBody executes one iteration of a loop's block in its own scope and handles any 'break' or 'continue' in it.
+// It reports whether the loop should stop, which is on a 'break' or a return out of the enclosing function.
+func (intptr *Interpreter) loopBody(block []Statement) (bool, error) {
+	if declares(block) {
+		intptr.env.push("loop")
+		defer intptr.env.pop()
+	}
+
+	for _, stmt := range block {
+		if err := stmt.execute(intptr); err != nil {
+			return true, err
+		}
+		if intptr.shouldBreak() {
+			break
+		}
+	}
+
+	if jump := intptr.loopJump; jump != nil {
+		intptr.loopJump = nil
+		return jump.is(Break), nil
+	}
+	return intptr.funcRet != nil, nil
+}
+
+// executeBlock executes the body of an if, try or finally, which only gets a block of its own if it declares something
+func (intptr *Interpreter) executeBlock(id string, block []Statement) error {
+	if declares(block) {
+		intptr.env.push(id)
+		defer intptr.env.pop()
+	}
+
+	for _, stmt := range block {
+		if err := stmt.execute(intptr); err != nil {
+			return err
+		}
+		if intptr.shouldBreak() {
+			break
+		}
+	}
+	return nil
+}
+
+// caught is the value a catch gets for err. A thrown value is caught as it is, and any other error
+// is caught as an Error with the kind of error it is, its message and the line it happened on.
+func (intptr *Interpreter) caught(err error) Value {
+	intptr.failed = nil
+	if thrown, ok := err.(Thrown); ok {
+		return thrown.value
+	}
+
+	var line Value
+	if at := errorToken(err); at.Line > 0 {
+		line = intValue(int(at.Line))
+	}
+	instance := JlangClassInstance{intptr.errors, NewEnvironment(intptr.errors.identifier.Lexeme)}
+	instance.initError(errorKind(err), stringValue(errorMessage(err)), line)
+	return instanceValue(instance)
+}
+
+// catchable is whether a script can catch err, which is anything but the interpreter itself going wrong
+func catchable(err error) bool {
+	_, internal := err.(InternalError)
+	return !internal
+}
+
 func (intptr *Interpreter) flush() {
 	intptr.s.flush()
 	intptr.p.flush()
 }
 
-func NewInterpreter() *Interpreter {
-	intptr := &Interpreter{env: NewEnvironment("global"), writeLog: log.New(os.Stdout, "", 0)}
+func NewInterpreter(options ...Option) *Interpreter {
+	intptr := &Interpreter{env: NewEnvironment("global"), maxDepth: DefaultMaxDepth, writeLog: log.New(os.Stdout, "", 0), warnLog: log.New(os.Stderr, "", 0)}
+	for _, option := range options {
+		option(intptr)
+	}
 	intptr.s = &Scanner{}
 	intptr.p = &Parser{}
+	intptr.r = &Resolver{}
 
-	// Set globals
-	for _, stmt := range globals() {
-		err := stmt.execute(intptr)
-		if err != nil {
-			panic(fmt.Errorf("couldn't load globals: %s", err))
-		}
+	// Set globals, which are compiled too when the VM is running programs
+	if err := intptr.interpret(Program{globals()}); err != nil {
+		panic(fmt.Errorf("couldn't load globals: %s", err))
 	}
+	errors, _ := intptr.env.vars[0].store.query("Error")
+	intptr.errors = errors.asClass()
 
 	return intptr
 }
@@ -2,6 +2,7 @@ package lang
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -93,6 +94,403 @@ func TestInterpretRectangle(t *testing.T) {
 	}
 }
 
+func TestInterpretLogical(t *testing.T) {
+	if err := genFile("logical"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterpretLoopControl(t *testing.T) {
+	out, err := genFileOutput("loopcontrol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "56\n1\n3\n5\n7\n9\n5\n1:0\n2:0\n2:1\n2\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretClosure(t *testing.T) {
+	out, err := genFileOutput("closure")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1\n2\n1\n42\n15\n10\n105\nglobal\nhello jlang\nfunc double(x)\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretLambda(t *testing.T) {
+	out, err := genFileOutput("lambda")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2\n4\n6\n16\n5\n-7\ncalled\nnot positive\n15\n9\nimmediately invoked\n9\nfunc(x)\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretInheritance(t *testing.T) {
+	out, err := genFileOutput("inheritance")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "shape with 0 sides\nrectangle with 4 sides\n6\na square with 4 sides\n16\n4\n12\n16\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretInvalidSuperclass(t *testing.T) {
+	intptr := NewInterpreter()
+	err := intptr.Interpret("var x = 1;\nclass A < x {}")
+	if reflect.TypeOf(err).Name() != "InvalidSuperclass" {
+		t.Errorf("expected an InvalidSuperclass error, got %v", err)
+	}
+}
+
+func TestInterpretNotCallable(t *testing.T) {
+	intptr := NewInterpreter()
+	err := intptr.Interpret("var y = 5;\ny(1);")
+	if reflect.TypeOf(err).Name() != "BadCall" {
+		t.Errorf("expected a BadCall error, got %v", err)
+	}
+}
+
+func TestInterpretNestedArray(t *testing.T) {
+	out, err := genFileOutput("nestedarray")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[[1, 2], [3, 4]]\n3\n[[1, 0, 0], [0, 1, 0], [0, 0, 1]]\n[1, 40]\ny\n3\n12\nsides: [3, 4, 12]\nshared\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretBadIndex(t *testing.T) {
+	inputs := map[string]string{
+		"var a = [1, 2];\nprint a[2];":      "OutOfBounds",
+		"var a = [1, 2];\na[-1] = 0;":       "OutOfBounds",
+		"var a = [[1], 2];\nprint a[1][0];": "BadIndex",
+		"var a = [1, 2];\nprint a[\"0\"];":  "BadIndex",
+	}
+	for input, name := range inputs {
+		intptr := NewInterpreter()
+		err := intptr.Interpret(input)
+		if err == nil || reflect.TypeOf(err).Name() != name {
+			t.Errorf("expected a %s error for %q, got %v", name, input, err)
+		}
+	}
+}
+
+func TestInterpretAppend(t *testing.T) {
+	out, err := genFileOutput("append")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[1, 2, 3]\n[1, 2, 3, 4]\n[1, 2, 3, 5]\n[1, 2, 3]\n[100, 2, 3, 4]\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretMap(t *testing.T) {
+	out, err := genFileOutput("map")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "{alice: 31, bob: 27}\n27\n3\n[alice, bob, carol]\n[32, 27, 45]\ntrue\ntrue\nfalse\nfalse\n<nil>\n" +
+		"{alice: 32, carol: 45}\none\n2\n{nested: yes}\na: 3\nb: 1\nc: 1\n{}\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretBadMapKey(t *testing.T) {
+	inputs := []string{
+		"var m = {[1]: 2};",
+		"var m = {};\nm[{}] = 1;",
+		"var m = {};\nprint m[func() {}];",
+	}
+	for _, input := range inputs {
+		intptr := NewInterpreter()
+		err := intptr.Interpret(input)
+		if err == nil || reflect.TypeOf(err).Name() != "BadIndex" {
+			t.Errorf("expected a BadIndex error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestInterpretStrings(t *testing.T) {
+	out, err := genFileOutput("strings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "she said \"hello\"\ncolumns:\tone\ttwo\nfirst line\nsecond line\nback\\slash\nsmile 😀\n" +
+		"usage: jlang [file]\n    runs \"file\" or starts the repl\n    \\n is not an escape here\n5\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretInterpolation(t *testing.T) {
+	out, err := genFileOutput("interpolation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "area is 12\nx: 3, y: 4, perimeter: 14\na 3x4 rectangle\nsides [3, 4, 5] has 3 with 5 last\n" +
+		"{three: 3} and 3\nnested 2 <nil> true\nescaped ${not interpolated}\nhello jlang!\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretEquality(t *testing.T) {
+	out, err := genFileOutput("equality")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Repeat("true\n", 15) + "false\ntrue\nfalse\ntrue\ntrue\ntrue\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretBadComparison(t *testing.T) {
+	inputs := map[string]string{
+		"print 1 < \"2\";":      "Invalid comparison between type int and string.",
+		"print \"a\" >= 1;":     "Invalid comparison between type string and int.",
+		"print true > false;":   "Invalid comparison between type bool and bool.",
+		"print [1] <= [\"1\"];": "Invalid comparison between type array and array.",
+		"print {} < {};":        "Invalid comparison between type map and map.",
+	}
+	for input, expected := range inputs {
+		intptr := NewInterpreter()
+		err := intptr.Interpret(input)
+		if _, ok := err.(InvalidTypeCombination); !ok {
+			t.Errorf("expected an InvalidTypeCombination error for %q, got %v", input, err)
+		} else if !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("expected %q for %q, got %q", expected, input, err)
+		}
+	}
+}
+
+func TestInterpretExceptions(t *testing.T) {
+	out, err := genFileOutput("exceptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2\nError: can't divide 1 by zero on line 4\nOutOfBounds\nDivisionByZero\n404\nNotFound\na.txt\n" +
+		"inner finally\nsecond from first\nfinally on return\ntry\nfinally\n" +
+		"0\nstep 0\nstep 1\n2\nstep 2\nstep 3\nbottom\nstill running\n"
+	if out != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestInterpretUncaught(t *testing.T) {
+	inputs := map[string]string{
+		"throw Error(\"bad\");":                       "Uncaught Error: bad\n",
+		"throw 42;":                                   "Uncaught 42\n",
+		"try { throw 1; } finally { }":                "Uncaught 1\n",
+		"try { throw 1; } catch (e) { throw e + 1; }": "Uncaught 2\n",
+	}
+	for input, expected := range inputs {
+		for _, vm := range []bool{false, true} {
+			intptr := NewInterpreter()
+			if vm {
+				intptr = NewInterpreter(WithVM())
+			}
+			err := intptr.Interpret(input)
+			if _, ok := err.(Thrown); !ok {
+				t.Errorf("expected a Thrown error for %q, got %v", input, err)
+			} else if !strings.HasPrefix(err.Error(), expected) {
+				t.Errorf("expected %q for %q, got %q", expected, input, err)
+			}
+		}
+	}
+}
+
+func TestInterpretTraceback(t *testing.T) {
+	input := "" +
+		"class A { func f(x) { return 1 / x; } }\n" +
+		"func g(x) {\n" +
+		"    var h = y => A().f(y);\n" +
+		"    return h(x);\n" +
+		"}\n" +
+		"try { g(0); } catch (e) { }\n" +
+		"print g(0);"
+	for _, vm := range []bool{false, true} {
+		intptr := NewInterpreter()
+		if vm {
+			intptr = NewInterpreter(WithVM())
+		}
+		intptr.HookLogOut(&strings.Builder{})
+		err := intptr.Interpret(input)
+		trace, ok := err.(Traceback)
+		if !ok {
+			t.Fatalf("expected a Traceback, got %v", err)
+		}
+		if _, ok := trace.Unwrap().(DivisionByZero); !ok {
+			t.Errorf("expected the Traceback of a DivisionByZero error, got %v", trace.Err)
+		}
+
+		expected := "Traceback (most recent call first):\n" +
+			"\tat A#f (1:34)\n" +
+			"\tat func (3:22)\n" +
+			"\tat g (4:12)\n" +
+			"\tat <script> (7:7)"
+		if !strings.HasSuffix(err.Error(), expected) {
+			t.Errorf("traceback did not match:\n%s\nwant:\n%s", err, expected)
+		}
+	}
+}
+
+func TestInterpretTracebackTopLevel(t *testing.T) {
+	intptr := NewInterpreter()
+	intptr.HookLogOut(&strings.Builder{})
+	// The error that was caught in f doesn't leave its calls behind for the next one
+	err := intptr.Interpret("func f() { try { [][0]; } catch (e) { print e; } }\nf();\nprint 1 / 0;")
+	if _, ok := err.(DivisionByZero); !ok {
+		t.Errorf("expected a DivisionByZero error without a Traceback, got %v", err)
+	}
+}
+
+func TestInterpretStackOverflow(t *testing.T) {
+	input := "" +
+		"func ping(n) { return pong(n + 1); }\n" +
+		"func pong(n) { return ping(n + 1); }\n" +
+		"try { ping(0); } catch (e) { print e.kind; }\n" +
+		"func count(n) { if n == 0 { return 0; } return count(n - 1) + 1; }\n" +
+		"print count(9);\n" +
+		"print count(10);"
+	for _, vm := range []bool{false, true} {
+		options := []Option{WithMaxDepth(10)}
+		if vm {
+			options = append(options, WithVM())
+		}
+		intptr := NewInterpreter(options...)
+		out := strings.Builder{}
+		intptr.HookLogOut(&out)
+		err := intptr.Interpret(input)
+		if out.String() != "StackOverflow\n9\n" {
+			t.Errorf("expected the StackOverflow to be caught and the limit to allow 10 calls, got %q", out.String())
+		}
+
+		trace, ok := err.(Traceback)
+		if !ok {
+			t.Fatalf("expected a Traceback, got %v", err)
+		}
+		overflow, ok := trace.Err.(StackOverflow)
+		if !ok {
+			t.Fatalf("expected a StackOverflow error, got %v", trace.Err)
+		}
+		if len(overflow.frames) != 1 || overflow.frames[0].Function != "count" {
+			t.Errorf("expected count to be the call that repeats, got %v", overflow.frames)
+		}
+		expected := "Traceback (most recent call first):\n" +
+			"\tat count (4:48)\n" +
+			"\t... the 1 calls above repeat 9 more times\n" +
+			"\tat <script> (6:7)"
+		if !strings.HasSuffix(err.Error(), expected) {
+			t.Errorf("traceback did not match:\n%s\nwant:\n%s", err, expected)
+		}
+	}
+}
+
+func TestInterpretSelfReference(t *testing.T) {
+	input := "" +
+		"class N { var n; var next; }\n" +
+		"var a = N();\n" +
+		"a.n = 1;\n" +
+		"a.next = a;\n" +
+		"var b = N();\n" +
+		"b.next = [a, a];\n" +
+		"print a;\n" +
+		"print \"${b}\";"
+	walked, compiled := runEngines(input)
+	expected := "N{n: 1, next: N{...}}\nN{n: <nil>, next: [N{n: 1, next: N{...}}, N{n: 1, next: N{...}}]}\n"
+	if walked != expected {
+		t.Errorf("output did not match:\n%s\nwant:\n%s", walked, expected)
+	}
+	if compiled != walked {
+		t.Errorf("ran differently on the VM:\n%s\nwant:\n%s", compiled, walked)
+	}
+}
+
+// TestInterpretFailureScripts runs the scripts in /tests/ that are there to fail
+func TestInterpretFailureScripts(t *testing.T) {
+	// Integer division makes the left side 0
+	_, err := genFileOutput("nestedmathfailure")
+	if zero, ok := err.(DivisionByZero); !ok {
+		t.Errorf("expected a DivisionByZero error, got %v", err)
+	} else if at := spanOf(zero.offender).Lexeme; at != "2 * 5 / (298 / 3 * 2)" {
+		t.Errorf("expected the error at '2 * 5 / (298 / 3 * 2)', got '%s'", at)
+	}
+
+	// A '!' expression can't be assigned to, so the statement ends before the '='
+	_, err = genFileOutput("truthyparsefailure")
+	list, ok := err.(ErrorList)
+	if !ok || len(list.Errors()) != 1 {
+		t.Fatalf("expected one syntax error, got %v", err)
+	}
+	if parseErr := list.Errors()[0].(ParseError); parseErr.token.Lexeme != "=" || parseErr.token.Line != 1 {
+		t.Errorf("expected the error at '=' on line 1, got %s", parseErr)
+	}
+}
+
+func TestErrorExcerpt(t *testing.T) {
+	intptr := NewInterpreter()
+	err := intptr.Interpret("var x = 1;\nprint x - \"a\" + 2;")
+	if err == nil {
+		t.Fatal("expected an InvalidTypeCombination error")
+	}
+	if reflect.TypeOf(err).Name() != "InvalidTypeCombination" {
+		t.Fatalf("expected an InvalidTypeCombination error, got %s", reflect.TypeOf(err).Name())
+	}
+
+	expected := "Invalid subtraction between type int and string.\n" +
+		" --> 2:9\n" +
+		"2 | print x - \"a\" + 2;\n" +
+		"  |         ^"
+	if err.Error() != expected {
+		t.Errorf("error did not match:\n%s\nwant:\n%s", err, expected)
+	}
+}
+
+func TestErrorExcerptArgumentMismatch(t *testing.T) {
+	intptr := NewInterpreter()
+	err := intptr.Interpret("func f(a, b) {\n    return a + b;\n}\nprint f(1);")
+	if reflect.TypeOf(err).Name() != "BadCall" {
+		t.Fatalf("expected a BadCall error, got %v", err)
+	}
+
+	// The call site is the only excerpt, the declaration is named by its line
+	expected := "bad call to 'f'\n" +
+		"\tmore: argument length mismatch for 'f' call: want 2, got 1 (declared on line 1).\n" +
+		" --> 4:7\n" +
+		"4 | print f(1);\n" +
+		"  |       ^"
+	if err.Error() != expected {
+		t.Errorf("error did not match:\n%s\nwant:\n%s", err, expected)
+	}
+}
+
 func genFile(filename string) error {
 	intptr := NewInterpreter()
 	if err := intptr.File("../tests/" + filename + ".jlang"); err != nil {
@@ -100,3 +498,12 @@ func genFile(filename string) error {
 	}
 	return nil
 }
+
+// genFileOutput is genFile but also returns everything the script printed
+func genFileOutput(filename string) (string, error) {
+	out := strings.Builder{}
+	intptr := NewInterpreter()
+	intptr.HookLogOut(&out)
+	err := intptr.File("../tests/" + filename + ".jlang")
+	return out.String(), err
+}
@@ -0,0 +1,80 @@
+package lang
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JlangMap is a map value i.e '{"one": 1, "two": 2}'. Like arrays, it's shared by reference
+// wherever it gets stored or passed. Keys are kept in the order they were first set, and equal keys
+// are the same key, so '1' and '1.0' get the same entry.
+type JlangMap struct {
+	keys    []Value         // As they were first set
+	entries map[Value]Value // By Value.key
+}
+
+func newJlangMap() *JlangMap {
+	return &JlangMap{make([]Value, 0), make(map[Value]Value)}
+}
+
+// checkKey makes sure the key can be used in a map, only scalar values can be
+func checkKey(key Value) error {
+	switch key.kind {
+	case NilKind, BoolKind, IntKind, NumberKind, StringKind:
+		return nil
+	}
+	return fmt.Errorf("type '%s' can't be used as a map key", key.kind)
+}
+
+func (m *JlangMap) get(key Value) Value {
+	return m.entries[key.key()]
+}
+
+func (m *JlangMap) set(key Value, val Value) {
+	if !m.has(key) {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key.key()] = val
+}
+
+func (m *JlangMap) has(key Value) bool {
+	_, found := m.entries[key.key()]
+	return found
+}
+
+// delete removes the key from the map, and returns whether it was there to remove
+func (m *JlangMap) delete(key Value) bool {
+	if !m.has(key) {
+		return false
+	}
+	delete(m.entries, key.key())
+	for i, k := range m.keys {
+		if k.key() == key.key() {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *JlangMap) String() string {
+	return m.format(nil)
+}
+
+// format is String for a map inside the containers in seen, the same as Value.format
+func (m *JlangMap) format(seen map[interface{}]bool) string {
+	if seen[m] {
+		return "{...}"
+	}
+	if seen == nil {
+		seen = make(map[interface{}]bool)
+	}
+	seen[m] = true
+	defer delete(seen, m)
+
+	entries := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		entries[i] = fmt.Sprintf("%s: %s", key.format(seen), m.get(key).format(seen))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
@@ -2,35 +2,120 @@ package lang
 
 import (
 	"fmt"
-	"reflect"
 )
 
 // Parser is how a Jlang token sequence gets parsed and turned into Expressions
 type Parser struct {
-	src     []Token
-	current uint
-	error   error
-	Errors  []error
-}
+	src       []Token
+	current   uint
+	error     error
+	panicking bool
+	loopDepth uint
+	classKind int
+	Errors    []error
+}
+
+// What kind of class declaration the parser is in, to know whether 'super' can be used
+const (
+	noClass = iota
+	inClass
+	inSubclass
+)
 
 // Parse takes a sequence of scanned Tokens and turns them into a corresponding Jlang Program statement
-// If the parser is unable to form a valid Program, it returns a ParseError specifying why it couldn't
+// If the parser is unable to form a valid Program, it keeps going to find as many syntax errors as it can
+// and returns an ErrorList with every ParseError it came across.
 func (p *Parser) Parse(tokens []Token) (*Program, error) {
 	p.src = tokens
 	p.flush()
 	statements := make([]Statement, 0)
 	for !p.isAtEnd() {
-		stmt, err := p.statement()
-		p.consume(Semicolon, "Want ';' to close statement.")
-		if err != nil {
-			return nil, err
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
 		}
-		statements = append(statements, stmt)
 	}
 
+	if len(p.Errors) > 0 {
+		return nil, ErrorList{p.Errors}
+	}
 	return &Program{statements}, nil
 }
 
+// ParseSource scans and parses a whole source into its Program without running it, for tools that work on the AST
+func ParseSource(source *Source) (*Program, error) {
+	scan := Scanner{}
+	tokens, err := scan.ScanSource(source)
+	if err != nil {
+		return nil, ScanError{err}
+	}
+	p := Parser{}
+	return p.Parse(append(tokens, scan.eof()))
+}
+
+// declaration parses a statement along with its closing ';'.
+// If the statement has a syntax error, the error is recorded and the parser synchronizes to the start of the
+// next statement so parsing can carry on. A nil Statement is returned in that case.
+func (p *Parser) declaration() Statement {
+	start := p.current
+	stmt, err := p.statement()
+	if err != nil {
+		p.record(err)
+	}
+	if !p.panicking {
+		// Statements that end with a block don't need a ';' after the closing '}'
+		if p.previous().is(RightBrace) {
+			p.match(Semicolon)
+		} else if !p.match(Semicolon) {
+			p.hadError(p.peek(), "Want ';' to close statement.")
+		}
+	}
+
+	if p.panicking {
+		p.synchronize(start)
+		return nil
+	}
+	return stmt
+}
+
+// synchronize discards tokens until it's at a statement boundary, which is right after a ';', or before a '}'
+// or statement keyword. start is where the failed statement began so that progress is always made.
+func (p *Parser) synchronize(start uint) {
+	p.panicking = false
+	if p.current == start {
+		p.advance()
+	}
+
+	for !p.isAtEnd() {
+		if p.previous().is(Semicolon) {
+			return
+		}
+		switch p.peek().Type {
+		case RightBrace, Class, Function, Var, For, If, While, Print, Return, Break, Continue, Try, Throw:
+			return
+		case LeftBrace:
+			// The block belongs to the broken statement so skip the whole thing
+			p.skipBlock()
+			return
+		}
+		p.advance()
+	}
+}
+
+func (p *Parser) skipBlock() {
+	depth := 0
+	for !p.isAtEnd() {
+		switch p.advance().Type {
+		case LeftBrace:
+			depth++
+		case RightBrace:
+			depth--
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
 func (p *Parser) statement() (Statement, error) {
 	switch token := p.advance(); token.Type {
 	case Print:
@@ -42,9 +127,9 @@ func (p *Parser) statement() (Statement, error) {
 			p.reverse().reverse()
 			return p.assignmentStatement()
 		}
-		if p.match(Dot) {
+		if p.match(Dot, LeftBracket, LeftParen) {
 			p.reverse().reverse()
-			return p.PropertyAssignmentStatement()
+			return p.MemberAssignmentStatement()
 		}
 	case If:
 		return p.IfStatement()
@@ -52,72 +137,128 @@ func (p *Parser) statement() (Statement, error) {
 		return p.WhileStatement()
 	case For:
 		return p.ForStatement()
+	case Try:
+		return p.TryStatement()
+	case Throw:
+		if expr := p.expression(); expr != nil {
+			return ThrowStatement{token, expr}, nil
+		}
+		return nil, p.error
 	case Function:
-		return p.FunctionDeclaration()
+		// 'func (' is an anonymous function expression instead of a declaration
+		if !p.peek().is(LeftParen) {
+			return p.FunctionDeclaration()
+		}
 	case Class:
 		return p.ClassDeclaration()
+	case Break:
+		if p.loopDepth == 0 {
+			return nil, p.hadError(token, "Can't use 'break' outside of a loop.")
+		}
+		return BreakStatement{token}, nil
+	case Continue:
+		if p.loopDepth == 0 {
+			return nil, p.hadError(token, "Can't use 'continue' outside of a loop.")
+		}
+		return ContinueStatement{token}, nil
 	case Return:
+		if p.peek().is(Semicolon) {
+			nilToken := token
+			nilToken.Lexeme, nilToken.Type = "retnil", Nil
+			return ReturnStatement{Literal{nilToken}, Value{}}, nil
+		}
 		if expr := p.expression(); expr != nil {
-			return ReturnStatement{expr, nil}, nil
-		} else if p.peek().is(Semicolon) {
-			return ReturnStatement{Literal{Token{"retnil", Nil, token.Line}}, nil}, nil
+			return ReturnStatement{expr, Value{}}, nil
 		}
+		return nil, p.error
 	}
 	p.reverse()
 
 	return p.ExpressionStatement()
 }
 
-func (p *Parser) PropertyAssignmentStatement() (Statement, error) {
-	var get, set Expression
-
-	get = p.property()
-	if reflect.TypeOf(get) == reflect.TypeOf(MethodInvocation{}) {
-		return ExpressionStatement{get}, nil
+// MemberAssignmentStatement is 'object.member = value' or 'array[index] = value', where the object or array can be
+// any chain of calls and accesses i.e 'grid[i][j] = 0' or 'shape.sides[1] = 4'.
+// Anything else is parsed as an ExpressionStatement instead, i.e 'rect.Print();'
+func (p *Parser) MemberAssignmentStatement() (Statement, error) {
+	start := p.current
+	get := p.call()
+	if get == nil {
+		return nil, p.error
+	}
+	if !p.peek().is(Equal) {
+		p.current = start
+		return p.ExpressionStatement()
 	}
-	p.consume(Equal, "Want '=' for property assignment.")
-	set = p.expression()
 
-	return PropertyAssignmentStatement{
-		get.(PropertyAccess),
-		set,
-	}, nil
+	switch target := get.(type) {
+	case PropertyAccess:
+		p.advance()
+		return PropertyAssignmentStatement{target, p.expression()}, nil
+	case ArrayAccess:
+		p.advance()
+		return IndexAssignmentStatement{target, p.expression()}, nil
+	}
+	return nil, p.hadError(spanOf(get), fmt.Sprintf("Can't assign to '%s'.", spanOf(get).Lexeme))
 }
 
 func (p *Parser) ClassDeclaration() (Statement, error) {
+	keyword := p.previous()
 	identifier := p.consume(Identifier, "Want identifier after 'class' keyword.")
-	p.consume(LeftBrace, "Expect '{' after class identifier.")
+	if identifier == nil {
+		return nil, p.error
+	}
+
+	var superclass *Variable = nil
+	classKind := p.classKind
+	p.classKind = inClass
+	defer func() { p.classKind = classKind }()
+	if p.match(Less) || (p.peek().is(Identifier) && p.peek().Lexeme == "extends" && p.match(Identifier)) {
+		super := p.consume(Identifier, "Want superclass identifier after '<'.")
+		if super == nil {
+			return nil, p.error
+		}
+		if super.Lexeme == identifier.Lexeme {
+			return nil, p.hadError(*super, fmt.Sprintf("Class '%s' can't inherit from itself.", identifier.Lexeme))
+		}
+		superclass = &Variable{*super, &local{}}
+		p.classKind = inSubclass
+	}
+
+	if p.consume(LeftBrace, "Expect '{' after class identifier.") == nil {
+		return nil, p.error
+	}
 	var constructor *FunctionDeclarationStatement = nil
 	varDecls := make([]VariableStatement, 0)
 	funcDecls := make([]FunctionDeclarationStatement, 0)
 
-	for true {
-		if p.match(RightBrace) {
-			break
+	for !p.match(RightBrace) {
+		if p.isAtEnd() {
+			return nil, p.hadError(p.peek(), fmt.Sprintf("Couldn't find '}' to close class '%s' before end of file.", identifier.Lexeme))
 		}
 		start := p.current
-		stmt, err := p.statement()
-		if err != nil {
-			return nil, err
+		stmt := p.declaration()
+		if stmt == nil {
+			continue
 		}
-		switch reflect.TypeOf(stmt) {
-		case reflect.TypeOf(FunctionDeclarationStatement{}):
-			funk := stmt.(FunctionDeclarationStatement)
+		switch member := stmt.(type) {
+		case FunctionDeclarationStatement:
+			funk := member
 			if identifier.Lexeme == funk.Identifier.Lexeme {
 				constructor = &funk
+				args := []Token{thisToken(constructor.Identifier.Line)}
 				if constructor.args != nil {
-					args := append([]Token{thisToken(constructor.Identifier.Line)}, *constructor.args...)
-					constructor.args = &args
+					args = append(args, *constructor.args...)
 				}
+				constructor.args = &args
 				continue
 			}
 			funcDecls = append(funcDecls, funk)
-		case reflect.TypeOf(VariableStatement{}):
-			varDecl := stmt.(VariableStatement)
-			varDecls = append(varDecls, varDecl)
-			p.consume(Semicolon, fmt.Sprintf("Expect ';' after '%s' member declaration.", varDecl.Identifier.Lexeme))
+		case VariableStatement:
+			varDecls = append(varDecls, member)
 		default:
-			return nil, InvalidClassStatement{*identifier, p.src[start]}
+			p.record(InvalidClassStatement{*identifier, p.src[start]})
+			p.panicking = false
 		}
 	}
 
@@ -136,23 +277,118 @@ func (p *Parser) ClassDeclaration() (Statement, error) {
 
 	return JlangClass{
 		*identifier,
+		keyword.Doc(),
 		nil,
+		nil,
+		nil,
+		Environment{},
 		struct {
+			superclass  *Variable
 			constructor *FunctionDeclarationStatement
 			varDecls    *[]VariableStatement
 			funcDecls   *[]FunctionDeclarationStatement
-		}{constructor, &varDecls, &funcDecls},
+		}{superclass, constructor, &varDecls, &funcDecls},
 	}, nil
 }
 
 func (p *Parser) FunctionDeclaration() (Statement, error) {
+	keyword := p.previous()
 	identifier := p.consume(Identifier, "Expect identifier after 'func' keyword.")
-	args := make([]Token, 0)
-	var block []Statement
+	if identifier == nil || p.consume(LeftParen, "Expect '(' after function identifier.") == nil {
+		return nil, p.error
+	}
+	args, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := p.functionBody()
+	if err != nil {
+		return nil, err
+	}
+
+	decl := newFunctionDeclaration(*identifier, args, block)
+	decl.Doc = keyword.Doc()
+	return decl, nil
+}
+
+// Lambda is an anonymous function expression, the 'func' keyword has already been consumed.
+func (p *Parser) Lambda() Expression {
+	keyword := p.previous()
+	if p.consume(LeftParen, "Expect '(' after 'func' keyword for anonymous function.") == nil {
+		return nil
+	}
+	args, err := p.parameters()
+	if err != nil {
+		return nil
+	}
+	block, err := p.functionBody()
+	if err != nil {
+		return nil
+	}
+
+	return Lambda{newFunctionDeclaration(keyword, args, block), keyword}
+}
+
+// ArrowFunction is the shorthand anonymous function '(x, y) => x * y' or 'x => x * 2'.
+// The body can also be a block like any other function i.e '(x) => { return x; }'
+func (p *Parser) ArrowFunction() Expression {
+	start := p.peek()
+	var args []Token
 	var err error
+	if p.match(LeftParen) {
+		if args, err = p.parameters(); err != nil {
+			return nil
+		}
+	} else if identifier := p.consume(Identifier, "Expect parameter for arrow function."); identifier != nil {
+		args = []Token{*identifier}
+	}
+	arrow := p.consume(Arrow, "Want '=>' after arrow function parameter(s).")
+	if arrow == nil {
+		return nil
+	}
 
-	p.consume(LeftParen, "Expect '(' after function identifier.")
+	var block []Statement
+	if p.peek().is(LeftBrace) {
+		if block, err = p.functionBody(); err != nil {
+			return nil
+		}
+	} else {
+		expr := p.expression()
+		if expr == nil {
+			return nil
+		}
+		block = []Statement{ReturnStatement{expr, Value{}}}
+	}
+
+	return Lambda{newFunctionDeclaration(*arrow, args, block), start}
+}
 
+// super is 'super.method' or 'super' for the superclass constructor, the 'super' keyword has already been consumed.
+func (p *Parser) super() Expression {
+	keyword := p.previous()
+	if p.classKind != inSubclass {
+		p.hadError(keyword, "Can't use 'super' outside of a class with a superclass.")
+		return nil
+	}
+	if !p.match(Dot) {
+		if !p.peek().is(LeftParen) {
+			p.hadError(p.peek(), "Want '.' or '(' after 'super'.")
+			return nil
+		}
+		return SuperAccess{keyword, nil}
+	}
+
+	method := p.consume(Identifier, "Want superclass method identifier after 'super.'.")
+	if method == nil {
+		return nil
+	}
+	return SuperAccess{keyword, method}
+}
+
+// parameters is the list of parameter names of a function declaration, the '(' has already been consumed.
+func (p *Parser) parameters() ([]Token, error) {
+	args := make([]Token, 0)
 	for p.match(Identifier) {
 		args = append(args, p.previous())
 		if p.peek().is(RightParen) {
@@ -164,41 +400,66 @@ func (p *Parser) FunctionDeclaration() (Statement, error) {
 		p.consume(Comma, "Expect ',' to separate parameter names.")
 	}
 
-	p.consume(RightParen, "Want ')' to close function parameter(s).")
-
-	if block, err = p.blockStatement("func"); err != nil {
-		return nil, err
+	if p.consume(RightParen, "Want ')' to close function parameter(s).") == nil {
+		return nil, p.error
 	}
+	return args, nil
+}
 
-	if len(args) == 0 {
-		return FunctionDeclarationStatement{*identifier, nil, 0, block}, nil
-	}
+func (p *Parser) functionBody() ([]Statement, error) {
+	// A function body is never inside of a loop even if it is declared in one
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	defer func() { p.loopDepth = loopDepth }()
+	return p.blockStatement("func")
+}
 
-	return FunctionDeclarationStatement{*identifier, &args, uint(len(args)), block}, nil
+// isArrowFunction looks ahead for the '(x, y) =>' or 'x =>' start of an arrow function
+// without consuming anything, since otherwise it's the start of a Grouping or Variable.
+func (p *Parser) isArrowFunction() bool {
+	i := p.current
+	if p.src[i].is(Identifier) {
+		return i+1 < uint(len(p.src)) && p.src[i+1].is(Arrow)
+	}
+	if !p.src[i].is(LeftParen) {
+		return false
+	}
+	for i++; i < uint(len(p.src)); i++ {
+		switch p.src[i].Type {
+		case Identifier, Comma:
+			continue
+		case RightParen:
+			return i+1 < uint(len(p.src)) && p.src[i+1].is(Arrow)
+		}
+		return false
+	}
+	return false
 }
 
 func (p *Parser) blockStatement(stmtType string) ([]Statement, error) {
-	p.consume(LeftBrace, fmt.Sprintf("Want '{' after %s statement.", stmtType))
+	if p.consume(LeftBrace, fmt.Sprintf("Want '{' after %s statement.", stmtType)) == nil {
+		return nil, p.error
+	}
 	block := make([]Statement, 0)
-	for true {
-		if p.peek().is(RightBrace) {
-			break
-		}
-		stmt, err := p.statement()
-		if err != nil {
-			return nil, err
-		}
-
-		p.consume(Semicolon, "Want ';' in block statement.")
-		block = append(block, stmt)
+	for !p.peek().is(RightBrace) {
 		if p.isAtEnd() {
-			return nil, ParseError{token: p.src[p.current], msg: fmt.Sprintf("Couldn't find '}' to close %s statement before end of file.", stmtType)}
+			return nil, p.hadError(p.peek(), fmt.Sprintf("Couldn't find '}' to close %s statement before end of file.", stmtType))
+		}
+		if stmt := p.declaration(); stmt != nil {
+			block = append(block, stmt)
 		}
 	}
 	p.consume(RightBrace, fmt.Sprintf("Want '}' to close %s statement.", stmtType))
 	return block, nil
 }
 
+// loopBlock is a blockStatement where 'break' and 'continue' are allowed
+func (p *Parser) loopBlock(stmtType string) ([]Statement, error) {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.blockStatement(stmtType)
+}
+
 func (p *Parser) variableStatement() (Statement, error) {
 	identifier := p.consume(Identifier, "Expect identifier after var keyword.")
 	if identifier == nil {
@@ -207,9 +468,6 @@ func (p *Parser) variableStatement() (Statement, error) {
 
 	var expr Expression
 	if p.match(Equal) {
-		if p.peek().is(LeftBracket) {
-			return p.ArrayDeclaration(*identifier)
-		}
 		expr = p.expression()
 	}
 
@@ -225,47 +483,33 @@ func (p *Parser) assignmentStatement() (Statement, error) {
 	}
 	expr := p.expression()
 
-	return AssignmentStatement{VariableStatement{identifier, expr}}, nil
-}
-
-func (p *Parser) ArrayDeclaration(identifier Token) (Statement, error) {
-	p.consume(LeftBracket, "Want '[' for array declaration.")
-	exprList := p.argsExprs(RightBracket)
-
-	p.consume(RightBracket, "Expect ']' to close array declaration.")
-	return ArrayDeclarationStatement{identifier, exprList}, nil
+	return AssignmentStatement{VariableStatement{identifier, expr}, &local{}}, nil
 }
 
 func (p *Parser) IfStatement() (Statement, error) {
 	expr := p.expression()
-	stmts := make([]Statement, 0)
-
-	p.consume(LeftBrace, "Expect '{' after if statement expression.")
-	for true {
-		if p.peek().is(RightBrace) {
-			p.consume(RightBrace, "Expect '}' to close if statement.")
-
-			// Build else block
-			if p.match(Else) {
-				elseBlock, err := p.blockStatement("else")
-				if err != nil {
-					return nil, err
-				}
-				return IfStatement{expr, stmts, &elseBlock}, nil
+	if expr == nil {
+		return nil, p.error
+	}
+	stmts, err := p.blockStatement("if")
+	if err != nil {
+		return nil, err
+	}
 
+	// Build else block, an 'else if' is an IfStatement nested as the only statement of the else block
+	if p.match(Else) {
+		if p.match(If) {
+			elseIf, err := p.IfStatement()
+			if err != nil {
+				return nil, err
 			}
-
-			return IfStatement{expr, stmts, nil}, nil
+			return IfStatement{expr, stmts, &[]Statement{elseIf}}, nil
 		}
-		stmt, err := p.statement()
+		elseBlock, err := p.blockStatement("else")
 		if err != nil {
 			return nil, err
 		}
-		p.consume(Semicolon, "Want ';' to end statement in if block.")
-		stmts = append(stmts, stmt)
-		if len(stmts) > 255 {
-			return nil, InternalError{42, "Maximum statements in if block reached."}
-		}
+		return IfStatement{expr, stmts, &elseBlock}, nil
 	}
 
 	return IfStatement{expr, stmts, nil}, nil
@@ -273,7 +517,7 @@ func (p *Parser) IfStatement() (Statement, error) {
 
 func (p *Parser) WhileStatement() (Statement, error) {
 	expr := p.expression()
-	stmts, err := p.blockStatement("while")
+	stmts, err := p.loopBlock("while")
 	if err != nil {
 		return nil, err
 	}
@@ -284,6 +528,46 @@ func (p *Parser) WhileStatement() (Statement, error) {
 	}, nil
 }
 
+// TryStatement is 'try { } catch (e) { } finally { }', where either the catch or the finally can be left out
+func (p *Parser) TryStatement() (Statement, error) {
+	keyword := p.previous()
+	stmts, err := p.blockStatement("try")
+	if err != nil {
+		return nil, err
+	}
+	stmt := TryStatement{keyword: keyword, block: stmts}
+
+	if p.match(Catch) {
+		if p.consume(LeftParen, "Want '(' after 'catch'.") == nil {
+			return nil, p.error
+		}
+		param := p.consume(Identifier, "Want a name for the error after 'catch ('.")
+		if param == nil {
+			return nil, p.error
+		}
+		if p.consume(RightParen, "Want ')' after the catch name.") == nil {
+			return nil, p.error
+		}
+		catchBlock, err := p.blockStatement("catch")
+		if err != nil {
+			return nil, err
+		}
+		stmt.catchParam, stmt.catchBlock = param, &catchBlock
+	}
+	if p.match(Finally) {
+		finallyBlock, err := p.blockStatement("finally")
+		if err != nil {
+			return nil, err
+		}
+		stmt.finallyBlock = &finallyBlock
+	}
+
+	if stmt.catchBlock == nil && stmt.finallyBlock == nil {
+		return nil, p.hadError(keyword, "Want 'catch' or 'finally' after try block.")
+	}
+	return stmt, nil
+}
+
 func (p *Parser) ForStatement() (Statement, error) {
 	var (
 		varStmt Statement
@@ -312,18 +596,21 @@ func (p *Parser) ForStatement() (Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		return nil, p.hadError(p.peek(), "Want assignment after for statement condition.")
 	}
 
-	stmts, err := p.blockStatement("for")
+	stmts, err := p.loopBlock("for")
 	if err != nil {
 		return nil, err
 	}
 
-	var retStmt VariableStatement
+	var retStmt *VariableStatement
 	if varStmt != nil {
-		retStmt = varStmt.(VariableStatement)
+		stmt := varStmt.(VariableStatement)
+		retStmt = &stmt
 	}
-	return ForStatement{&retStmt, test, assign.(AssignmentStatement), stmts}, nil
+	return ForStatement{retStmt, test, assign.(AssignmentStatement), stmts}, nil
 }
 
 func (p *Parser) ExpressionStatement() (Statement, error) {
@@ -342,7 +629,27 @@ func (p *Parser) PrintStatement() (Statement, error) {
 
 /********** Recursive descent parsing **********/
 func (p *Parser) expression() Expression {
-	return p.equality()
+	return p.or()
+}
+
+func (p *Parser) or() Expression {
+	expr := p.and()
+	for p.match(Or) {
+		op := Operator{p.previous()}
+		right := p.and()
+		expr = Logical{expr, op, right}
+	}
+	return expr
+}
+
+func (p *Parser) and() Expression {
+	expr := p.equality()
+	for p.match(And) {
+		op := Operator{p.previous()}
+		right := p.equality()
+		expr = Logical{expr, op, right}
+	}
+	return expr
 }
 
 func (p *Parser) equality() Expression {
@@ -406,27 +713,23 @@ func (p *Parser) unary() Expression {
 	return p.call()
 }
 
+// call is any chain of calls, property accesses, method invocations and indexing on a primary expression
 func (p *Parser) call() Expression {
-	if p.match(Identifier) {
-		if p.peek().is(LeftParen) {
-			identifier := p.previous()
-			p.consume(LeftParen, fmt.Sprintf("Expected '(' after identifier '%s'.", identifier.Lexeme))
-			args := p.argsExprs(RightParen)
-			p.consume(RightParen, fmt.Sprintf("Expected ')' to close call to '%s'.", identifier.Lexeme))
-			return Call{identifier, &args}
-		}
-		p.reverse()
-	}
-
-	return p.property()
-}
-
-func (p *Parser) property() Expression {
 	expr := p.primary()
 
-	for true {
-		if p.match(Dot) {
+	for expr != nil {
+		if p.match(LeftParen) {
+			args := p.argsExprs(RightParen)
+			paren := p.consume(RightParen, fmt.Sprintf("Expected ')' to close call to '%s'.", spanOf(expr).Lexeme))
+			if paren == nil {
+				return nil
+			}
+			expr = Call{expr, *paren, &args}
+		} else if p.match(Dot) {
 			identifier := p.consume(Identifier, "Expect identifier after '.' for property access.")
+			if identifier == nil {
+				return nil
+			}
 			if p.match(LeftParen) {
 				args := p.argsExprs(RightParen)
 				p.consume(RightParen, "Expect ')' to close method call.")
@@ -438,6 +741,16 @@ func (p *Parser) property() Expression {
 				continue
 			}
 			expr = PropertyAccess{expr, *identifier}
+		} else if p.match(LeftBracket) {
+			index := p.expression()
+			if index == nil {
+				return nil
+			}
+			bracket := p.consume(RightBracket, "Want ']' to close array index expression.")
+			if bracket == nil {
+				return nil
+			}
+			expr = ArrayAccess{expr, index, *bracket}
 		} else {
 			break
 		}
@@ -459,36 +772,119 @@ func (p *Parser) primary() Expression {
 	if p.match(Number, String) {
 		return Literal{p.previous()}
 	}
+	if p.match(StringPart) {
+		return p.interpolation()
+	}
+	if p.match(Function) {
+		return p.Lambda()
+	}
+	if p.match(Super) {
+		return p.super()
+	}
+	if p.isArrowFunction() {
+		return p.ArrowFunction()
+	}
 	if p.match(Identifier) {
-		if p.peek().is(LeftBracket) {
-			identifier := p.previous()
-			p.advance()
-			if index := p.expression(); index != nil {
-				p.consume(RightBracket, "Want ']' to close array index expression.")
-				return ArrayAccess{identifier, index}
-			}
+		return Variable{p.previous(), &local{}}
+	}
+	if p.match(LeftBracket) {
+		bracket := p.previous()
+		elements := p.argsExprs(RightBracket)
+		closing := p.consume(RightBracket, "Expect ']' to close array.")
+		if closing == nil {
+			return nil
 		}
-		return Variable{p.previous()}
+		return ArrayLiteral{bracket, elements, *closing}
+	}
+	if p.match(LeftBrace) {
+		return p.mapLiteral()
 	}
 
 	if p.match(LeftParen) {
+		paren := p.previous()
 		expr := p.expression()
-		p.consume(RightParen, "Expect ')' after expression.")
-		return Grouping{expr}
+		closing := p.consume(RightParen, "Expect ')' after expression.")
+		if closing == nil {
+			return nil
+		}
+		return Grouping{expr, paren.through(*closing)}
 	}
 
 	p.error = p.hadError(p.src[p.current], "Expected expression.")
 	return nil
 }
 
+// interpolation is the parts of an interpolated string, the first StringPart has already been consumed.
+// The scanner has already split it up so it's each StringPart followed by its expression, then the rest of the string.
+func (p *Parser) interpolation() Expression {
+	parts := make([]Expression, 0)
+	for {
+		part := p.previous()
+		part.Type = String
+		parts = append(parts, Literal{part})
+
+		// The rest of the string straight after the '${' means there's nothing in between
+		if closesInterpolation(p.peek()) {
+			p.hadError(part.last(2), "Expected expression.")
+			return nil
+		}
+		expr := p.expression()
+		if expr == nil {
+			return nil
+		}
+		parts = append(parts, expr)
+		if p.match(StringPart) {
+			continue
+		}
+		rest := p.consume(String, "Want '}' to close '${' in string.")
+		if rest == nil {
+			return nil
+		}
+		return Interpolation{append(parts, Literal{*rest})}
+	}
+}
+
+// closesInterpolation is whether t is the rest of a string after a '${...}', as opposed to a string nested in it
+func closesInterpolation(t Token) bool {
+	return (t.is(String) || t.is(StringPart)) && t.src != nil && t.End > t.Start && t.src.Text[t.Start] == '}'
+}
+
+// mapLiteral is the 'key: value' pairs of a map expression, the '{' has already been consumed.
+func (p *Parser) mapLiteral() Expression {
+	brace := p.previous()
+	keys, values := make([]Expression, 0), make([]Expression, 0)
+	for !p.peek().is(RightBrace) {
+		key := p.expression()
+		if key == nil || p.consume(Colon, "Want ':' after map key.") == nil {
+			return nil
+		}
+		value := p.expression()
+		if value == nil {
+			return nil
+		}
+		keys, values = append(keys, key), append(values, value)
+		if p.peek().is(RightBrace) || p.consume(Comma, "Want ',' after map entry.") == nil {
+			break
+		}
+	}
+
+	closing := p.consume(RightBrace, "Expect '}' to close map.")
+	if closing == nil {
+		return nil
+	}
+	return MapLiteral{brace, keys, values, *closing}
+}
+
 func (p *Parser) argsExprs(delim int) []Expression {
-	expr := p.expression()
 	args := make([]Expression, 0)
+	if p.peek().is(delim) {
+		return args
+	}
+
+	expr := p.expression()
 	for expr != nil {
 		args = append(args, expr)
-		if !p.peek().is(delim) {
-			p.consume(Comma, "Want ',' after argument.")
-		} else {
+		if p.peek().is(delim) || p.consume(Comma, "Want ',' after argument.") == nil {
 			break
 		}
 
@@ -574,17 +970,37 @@ func (p *Parser) isAtEnd() bool {
 
 func (p *Parser) hadError(token Token, msg string) ParseError {
 	err := ParseError{token, msg}
-	p.Errors = append(p.Errors, err)
-	p.error = err
-	//RuntimeError(err)
+	p.record(err)
 	return err
 }
 
+// record keeps track of a syntax error and puts the parser into panic mode until it synchronizes.
+// Errors while already panicking are dropped since they are most likely cascading from the first one.
+func (p *Parser) record(err error) {
+	p.error = err
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.Errors = append(p.Errors, err)
+}
+
 func (p *Parser) flush() {
 	p.current = 0
+	p.error = nil
+	p.panicking = false
+	p.loopDepth = 0
+	p.classKind = noClass
 	p.Errors = make([]error, 0)
 }
 
+func newFunctionDeclaration(identifier Token, args []Token, block []Statement) FunctionDeclarationStatement {
+	if len(args) == 0 {
+		return FunctionDeclarationStatement{identifier, nil, 0, block, ""}
+	}
+	return FunctionDeclarationStatement{identifier, &args, uint(len(args)), block, ""}
+}
+
 func thisToken(line uint) Token {
 	return Token{
 		Lexeme: "this",
@@ -1 +1,324 @@
 package lang
+
+import (
+	"testing"
+)
+
+func parseSource(t *testing.T, input string) (*Program, error) {
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Parser{}
+	return p.Parse(append(tokens, scan.eof()))
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	input := "" +
+		"var a = 1 +;\n" +
+		"func f(x {\n" +
+		"    print x;\n" +
+		"}\n" +
+		"func g(y) {\n" +
+		"    var z = ;\n" +
+		"    return y;\n" +
+		"}\n" +
+		"print g(2)\n"
+
+	_, err := parseSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+
+	// The missing ';' is reported at what was found instead, which is the end of the input
+	expectedLines := []uint{1, 2, 6, 10}
+	if len(list.Errors()) != len(expectedLines) {
+		t.Fatalf("expected %d errors, got %d:\n%s", len(expectedLines), len(list.Errors()), list)
+	}
+	for i, err := range list.Errors() {
+		parseErr, ok := err.(ParseError)
+		if !ok {
+			t.Errorf("expected a ParseError, got %v", err)
+			continue
+		}
+		if parseErr.token.Line != expectedLines[i] {
+			t.Errorf("expected error on line %d, got %d: %s", expectedLines[i], parseErr.token.Line, parseErr)
+		}
+	}
+}
+
+func TestParseMissingSemicolon(t *testing.T) {
+	input := "" +
+		"var vec = [1, 2]\n" +
+		"var T = 1;\n" +
+		"print 1 and2;\n"
+
+	_, err := parseSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	expected := []string{"var", "and2"}
+	if len(list.Errors()) != len(expected) {
+		t.Fatalf("expected %d errors, got %d:\n%s", len(expected), len(list.Errors()), list)
+	}
+	for i, err := range list.Errors() {
+		parseErr := err.(ParseError)
+		if parseErr.token.Lexeme != expected[i] || parseErr.msg != "Want ';' to close statement." {
+			t.Errorf("expected a missing ';' at '%s', got %s", expected[i], parseErr)
+		}
+	}
+}
+
+func TestParseOptionalBlockSemicolon(t *testing.T) {
+	input := "" +
+		"func f() { return 1; };\n" +
+		"if true { print f(); }\n" +
+		"while false { print 2; };\n" +
+		"print f();\n"
+
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(program.Statements) != 4 {
+		t.Errorf("expected 4 statements, got %d", len(program.Statements))
+	}
+}
+
+func TestParseElseIf(t *testing.T) {
+	input := "" +
+		"if x == 1 { print 1; }\n" +
+		"else if x == 2 { print 2; }\n" +
+		"else if x == 3 { print 3; }\n" +
+		"else { print 4; }\n"
+
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	depth := 0
+	stmt := program.Statements[0].(IfStatement)
+	for stmt.elseBlock != nil {
+		next, ok := (*stmt.elseBlock)[0].(IfStatement)
+		if !ok {
+			break
+		}
+		depth++
+		stmt = next
+	}
+	if depth != 2 {
+		t.Errorf("expected 2 nested else if statements, got %d", depth)
+	}
+	if stmt.elseBlock == nil || len(*stmt.elseBlock) != 1 {
+		t.Error("expected the last else if to have an else block")
+	}
+}
+
+func TestParseLoopControlOutsideLoop(t *testing.T) {
+	input := "" +
+		"break;\n" +
+		"while true {\n" +
+		"    func f() { continue; }\n" +
+		"    if true { break; }\n" +
+		"    continue;\n" +
+		"}\n"
+
+	_, err := parseSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	if len(list.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d:\n%s", len(list.Errors()), list)
+	}
+	if line := list.Errors()[1].(ParseError).token.Line; line != 3 {
+		t.Errorf("expected the 'continue' in the function body to be an error, got line %d", line)
+	}
+}
+
+func TestParseTry(t *testing.T) {
+	input := "" +
+		"try { throw 1; } catch (e) { print e; }\n" +
+		"try { print 1; } finally { print 2; }\n" +
+		"try { print 1; }\n" +
+		"try { print 1; } catch e { print e; }\n"
+
+	_, err := parseSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	if len(list.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d:\n%s", len(list.Errors()), list)
+	}
+	for i, line := range []uint{3, 4} {
+		if got := list.Errors()[i].(ParseError).token.Line; got != line {
+			t.Errorf("expected error %d on line %d, got line %d", i, line, got)
+		}
+	}
+}
+
+func TestParseArrowFunction(t *testing.T) {
+	input := "" +
+		"var f = (a, b) => a + b;\n" +
+		"var g = x => x;\n" +
+		"var h = (a) + (b);\n"
+
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, arity := range []uint{2, 1} {
+		lambda, ok := program.Statements[i].(VariableStatement).Expr.(Lambda)
+		if !ok {
+			t.Errorf("expected statement %d to be a Lambda", i)
+			continue
+		}
+		if lambda.decl.arity != arity {
+			t.Errorf("expected arity %d, got %d", arity, lambda.decl.arity)
+		}
+	}
+	if _, ok := program.Statements[2].(VariableStatement).Expr.(Binary); !ok {
+		t.Error("expected groupings to not be parsed as an arrow function")
+	}
+}
+
+func TestParseSuperOutsideSubclass(t *testing.T) {
+	input := "" +
+		"class A { func f() { return super.f(); } }\n" +
+		"class B < A { func f() { return super.f(); } }\n" +
+		"print super.f();\n"
+
+	_, err := parseSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	if len(list.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d:\n%s", len(list.Errors()), list)
+	}
+	for i, line := range []uint{1, 3} {
+		if got := list.Errors()[i].(ParseError).token.Line; got != line {
+			t.Errorf("expected error on line %d, got %d", line, got)
+		}
+	}
+}
+
+func TestParseIndexAssignment(t *testing.T) {
+	input := "" +
+		"grid[0][1] = 2;\n" +
+		"rect.sides()[0] = 4;\n" +
+		"rect.sides[0] = 4;\n" +
+		"rect.width = 4;\n" +
+		"print [[1, 2], [3]][0];\n"
+
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, ok := program.Statements[i].(IndexAssignmentStatement); !ok {
+			t.Errorf("expected statement %d to be an IndexAssignmentStatement", i)
+		}
+	}
+	if _, ok := program.Statements[3].(PropertyAssignmentStatement); !ok {
+		t.Error("expected statement 3 to be a PropertyAssignmentStatement")
+	}
+	access, ok := program.Statements[4].(PrintStatement).Expression.(ArrayAccess)
+	if !ok {
+		t.Fatal("expected indexing an array literal to be an ArrayAccess")
+	}
+	if literal, ok := access.Expr.(ArrayLiteral); !ok || len(literal.elements) != 2 {
+		t.Error("expected a nested ArrayLiteral with 2 elements")
+	}
+
+	if _, err := parseSource(t, "f() = 1;"); err == nil {
+		t.Error("expected assigning to a call to be an error")
+	}
+}
+
+func TestParseMapLiteral(t *testing.T) {
+	program, err := parseSource(t, "var m = {\"a\": 1, 2: [3], \"b\": {}};\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	literal, ok := program.Statements[0].(VariableStatement).Expr.(MapLiteral)
+	if !ok {
+		t.Fatal("expected a MapLiteral")
+	}
+	if len(literal.keys) != 3 || len(literal.values) != 3 {
+		t.Errorf("expected 3 entries, got %d keys and %d values", len(literal.keys), len(literal.values))
+	}
+
+	if _, err := parseSource(t, "var m = {\"a\" 1};"); err == nil {
+		t.Error("expected a map entry without ':' to be an error")
+	}
+}
+
+func TestParseInterpolation(t *testing.T) {
+	program, err := parseSource(t, "print \"a ${x} b ${y + 1}\";\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interp, ok := program.Statements[0].(PrintStatement).Expression.(Interpolation)
+	if !ok {
+		t.Fatal("expected an Interpolation")
+	}
+	if len(interp.parts) != 5 {
+		t.Errorf("expected 5 parts, got %d", len(interp.parts))
+	}
+	if span := spanOf(interp); span.Lexeme != "\"a ${x} b ${y + 1}\"" {
+		t.Errorf("expected the span to be the whole string, got %s", span.Lexeme)
+	}
+
+	if _, err := parseSource(t, "print \"a ${x y}\";"); err == nil {
+		t.Error("expected two expressions in one interpolation to be an error")
+	}
+
+	// An empty interpolation is a missing expression at its '${'
+	for input, column := range map[string]uint{"print \"${}\";": 8, "print \"a ${x} b ${} c\";": 17, "print \"${\"${}\"}\";": 11} {
+		_, err := parseSource(t, input)
+		list, ok := err.(ErrorList)
+		if !ok || len(list.Errors()) != 1 {
+			t.Errorf("expected one error for %q, got %v", input, err)
+			continue
+		}
+		parseErr := list.Errors()[0].(ParseError)
+		if parseErr.token.Lexeme != "${" || parseErr.token.Column != column || parseErr.msg != "Expected expression." {
+			t.Errorf("expected a missing expression at the '${' in column %d for %q, got %s", column, input, parseErr)
+		}
+	}
+}
+
+func TestParseDocComments(t *testing.T) {
+	input := "" +
+		"// Shape is anything with an area.\n" +
+		"class Shape {\n" +
+		"    /* The area of the shape. */\n" +
+		"    func area() { return 0; }\n" +
+		"}\n" +
+		"// Not a doc comment since there's a blank line.\n" +
+		"\n" +
+		"func f() {}\n"
+
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	class := program.Statements[0].(JlangClass)
+	if class.Doc != "Shape is anything with an area." {
+		t.Errorf("class doc did not match, got %q", class.Doc)
+	}
+	if method := (*class.Stmt.funcDecls)[0]; method.Doc != "The area of the shape." {
+		t.Errorf("method doc did not match, got %q", method.Doc)
+	}
+	if doc := program.Statements[1].(FunctionDeclarationStatement).Doc; doc != "" {
+		t.Errorf("expected no doc, got %q", doc)
+	}
+}
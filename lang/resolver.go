@@ -0,0 +1,327 @@
+package lang
+
+import "sort"
+
+// local is where the Resolver found a variable, depth blocks out from the innermost block at the slot it was
+// declared in. Globals have a depth of -1 and are looked up by name, since the REPL keeps declaring more of them.
+type local struct {
+	resolved bool
+	depth    int
+	slot     int
+}
+
+const (
+	varBinding = iota
+	paramBinding
+	funcBinding
+	classBinding
+)
+
+// binding is a name declared in a scope
+type binding struct {
+	token Token
+	slot  int
+	kind  int
+	used  bool
+}
+
+// scope is a block the Resolver is in. It's pushed and popped in the same places the interpreter pushes and
+// pops blocks, so the depth and slot of a variable are the same at run time.
+type scope struct {
+	bindings  map[string]*binding
+	order     []*binding
+	pending   map[string][]*reference // Names that were looked up through the scope before they were declared in it
+	functions int                     // How many function bodies deep the scope is
+}
+
+// reference is a name that was looked up before it was declared, which a later declaration can still resolve
+type reference struct {
+	name      Token
+	to        *local
+	read      bool
+	scopes    int  // How many scopes deep it was looked up from
+	functions int  // How many function bodies deep it was looked up from
+	resolved  bool // Whether a later declaration resolved it
+}
+
+// Resolver checks the scopes of a Program before it runs. Every Variable is resolved to the block and slot it
+// will be in when the Program runs, and undefined variables, variables used before they're declared and
+// variables declared twice in the same scope are errors. Local variables that are never used are Warnings.
+type Resolver struct {
+	Errors   []error
+	Warnings []error
+
+	scopes     []*scope
+	functions  int              // How many function bodies deep the Resolver is, code in them runs later
+	globals    map[string]bool  // The globals that are declared so far
+	declared   map[string]Token // Globals declared so far by the Program
+	topLevel   map[string]Token // Every global the Program declares
+	unresolved []*reference     // Names that aren't locals or globals, unless they turn out to be declared later
+	reported   map[uint]bool    // Where a use before declare was already reported
+}
+
+// Resolve resolves every Variable in program. globals are the names already declared in the global block,
+// i.e the builtins and whatever the REPL declared before. The returned error is an ErrorList of every error found.
+func (r *Resolver) Resolve(program *Program, globals []string) error {
+	r.flush()
+	for _, global := range globals {
+		r.globals[global] = true
+	}
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case VariableStatement:
+			r.topLevel[s.Identifier.Lexeme] = s.Identifier
+		case FunctionDeclarationStatement:
+			r.topLevel[s.Identifier.Lexeme] = s.Identifier
+		case JlangClass:
+			r.topLevel[s.identifier.Lexeme] = s.identifier
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		r.resolve(stmt)
+	}
+	for _, ref := range r.unresolved {
+		if !ref.resolved && !r.reported[ref.name.Start] {
+			r.Errors = append(r.Errors, UndefinedVariable{ref.name})
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		sort.SliceStable(r.Errors, func(i, j int) bool {
+			return errorToken(r.Errors[i]).Start < errorToken(r.Errors[j]).Start
+		})
+		return ErrorList{r.Errors}
+	}
+	return nil
+}
+
+func (r *Resolver) flush() {
+	r.Errors, r.Warnings = nil, nil
+	r.scopes, r.functions = nil, 0
+	r.globals = make(map[string]bool)
+	r.declared = make(map[string]Token)
+	r.topLevel = make(map[string]Token)
+	r.unresolved = nil
+	r.reported = make(map[uint]bool)
+}
+
+func (r *Resolver) resolve(node Node) {
+	Inspect(node, r.visit)
+}
+
+// visit resolves the nodes that declare or use variables, or open a scope. Everything else is walked through.
+func (r *Resolver) visit(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return false
+	case Variable:
+		r.reference(n.identifier, n.local, true)
+	case VariableStatement:
+		r.variable(n)
+	case AssignmentStatement:
+		r.resolve(n.Expr)
+		r.reference(n.Identifier, n.local, false)
+	case FunctionDeclarationStatement:
+		r.declare(n.Identifier, funcBinding)
+		r.function(n)
+	case Lambda:
+		r.function(n.decl)
+	case JlangClass:
+		r.class(n)
+	case IfStatement:
+		r.resolve(n.Expr)
+		r.block(n.block)
+		if n.elseBlock != nil {
+			r.block(*n.elseBlock)
+		}
+	case WhileStatement:
+		r.resolve(n.test)
+		r.block(n.block)
+	case TryStatement:
+		r.block(n.block)
+		if n.catchBlock != nil {
+			// The catch always gets a block of its own for the error to be declared in
+			r.beginScope()
+			r.declare(*n.catchParam, paramBinding)
+			for _, stmt := range *n.catchBlock {
+				r.resolve(stmt)
+			}
+			r.endScope()
+		}
+		if n.finallyBlock != nil {
+			r.block(*n.finallyBlock)
+		}
+	case ForStatement:
+		r.beginScope()
+		if n.varStmt != nil {
+			r.variable(*n.varStmt)
+		}
+		r.resolve(n.test)
+		r.resolve(n.assign)
+		r.block(n.block)
+		r.endScope()
+	default:
+		return true
+	}
+	return false
+}
+
+func (r *Resolver) variable(stmt VariableStatement) {
+	if stmt.Expr != nil {
+		r.resolve(stmt.Expr)
+	}
+	r.declare(stmt.Identifier, varBinding)
+}
+
+// function resolves a function body, which runs in its own block with the parameters declared first
+func (r *Resolver) function(decl FunctionDeclarationStatement) {
+	r.functions++
+	r.beginScope()
+	if decl.args != nil {
+		for _, param := range *decl.args {
+			r.declare(param, paramBinding)
+		}
+	}
+	for _, stmt := range decl.block {
+		r.resolve(stmt)
+	}
+	r.endScope()
+	r.functions--
+}
+
+// class resolves a class the same way JlangClass.execute sets it up. Member variables are initialized in the
+// scope the class is declared in, and methods see the superclass as 'super' in a block of its own.
+func (r *Resolver) class(class JlangClass) {
+	if class.Stmt.superclass != nil {
+		r.resolve(*class.Stmt.superclass)
+	}
+	r.declare(class.identifier, classBinding)
+
+	r.functions++
+	if varDecls := class.Stmt.varDecls; varDecls != nil {
+		for _, varDecl := range *varDecls {
+			if varDecl.Expr != nil {
+				r.resolve(varDecl.Expr)
+			}
+		}
+	}
+	r.functions--
+
+	if class.Stmt.superclass != nil {
+		r.beginScope()
+		r.declare(Token{Lexeme: "super", Type: Super}, paramBinding)
+	}
+	if class.Stmt.constructor != nil {
+		r.function(*class.Stmt.constructor)
+	}
+	if funcDecls := class.Stmt.funcDecls; funcDecls != nil {
+		for _, funcDecl := range *funcDecls {
+			r.function(funcDecl)
+		}
+	}
+	if class.Stmt.superclass != nil {
+		r.endScope()
+	}
+}
+
+// block resolves the body of an if, else, loop, try or finally, which only gets a scope if it declares something
+func (r *Resolver) block(stmts []Statement) {
+	if !declares(stmts) {
+		for _, stmt := range stmts {
+			r.resolve(stmt)
+		}
+		return
+	}
+	r.beginScope()
+	for _, stmt := range stmts {
+		r.resolve(stmt)
+	}
+	r.endScope()
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, &scope{make(map[string]*binding), nil, make(map[string][]*reference), r.functions})
+}
+
+// endScope pops the innermost scope, any variable in it that was never used is a warning
+func (r *Resolver) endScope() {
+	for _, b := range r.scopes[len(r.scopes)-1].order {
+		if b.kind == varBinding && !b.used {
+			r.Warnings = append(r.Warnings, UnusedVariable{b.token})
+		}
+	}
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare adds name to the innermost scope in the next slot, or to the globals outside of any scope
+func (r *Resolver) declare(name Token, kind int) {
+	if len(r.scopes) == 0 {
+		if previous, found := r.declared[name.Lexeme]; found {
+			r.Errors = append(r.Errors, DuplicateDeclaration{name, previous})
+		}
+		r.declared[name.Lexeme] = name
+		r.globals[name.Lexeme] = true
+		return
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	if previous, found := scope.bindings[name.Lexeme]; found {
+		r.Errors = append(r.Errors, DuplicateDeclaration{name, previous.token})
+		return
+	}
+	b := &binding{name, len(scope.order), kind, false}
+	for _, ref := range scope.pending[name.Lexeme] {
+		switch {
+		case ref.resolved:
+		case ref.functions > scope.functions:
+			// A function body that uses it runs later, after it's declared
+			*ref.to = local{true, ref.scopes - len(r.scopes), b.slot}
+			ref.resolved, b.used = true, b.used || ref.read
+		default:
+			r.useBeforeDeclare(ref.name, name)
+		}
+	}
+	delete(scope.pending, name.Lexeme)
+
+	scope.bindings[name.Lexeme] = b
+	scope.order = append(scope.order, b)
+}
+
+// reference resolves name to the innermost scope it's declared in. read is whether the variable's value is used,
+// as opposed to it being assigned to.
+func (r *Resolver) reference(name Token, to *local, read bool) {
+	if to == nil {
+		to = &local{}
+	}
+	ref := &reference{name, to, read, len(r.scopes), r.functions, false}
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		scope := r.scopes[i]
+		if b, found := scope.bindings[name.Lexeme]; found {
+			*to = local{true, len(r.scopes) - 1 - i, b.slot}
+			b.used = b.used || read
+			return
+		}
+		scope.pending[name.Lexeme] = append(scope.pending[name.Lexeme], ref)
+	}
+
+	*to = local{true, -1, 0}
+	if r.globals[name.Lexeme] {
+		return
+	}
+	if declaration, found := r.topLevel[name.Lexeme]; found {
+		// Functions can use globals that are declared after them, as long as they're called after
+		if r.functions == 0 {
+			r.useBeforeDeclare(name, declaration)
+		}
+		return
+	}
+	r.unresolved = append(r.unresolved, ref)
+}
+
+func (r *Resolver) useBeforeDeclare(reference Token, declaration Token) {
+	if !r.reported[reference.Start] {
+		r.reported[reference.Start] = true
+		r.Errors = append(r.Errors, UseBeforeDeclare{reference, declaration})
+	}
+}
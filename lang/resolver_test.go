@@ -0,0 +1,191 @@
+package lang
+
+import (
+	"strings"
+	"testing"
+)
+
+func resolveSource(t *testing.T, input string) (*Program, *Resolver, error) {
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Resolver{}
+	return program, r, r.Resolve(program, []string{"len"})
+}
+
+func TestResolveErrors(t *testing.T) {
+	input := "" +
+		"print early;\n" +
+		"var early = 1;\n" +
+		"func f(a, a) {\n" +
+		"    var b = b;\n" +
+		"    print c;\n" +
+		"    var c = 2;\n" +
+		"    return typo + len(later);\n" +
+		"}\n" +
+		"var early = 3;\n" +
+		"var later = 4;\n"
+
+	_, _, err := resolveSource(t, input)
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+
+	expected := []struct {
+		kind string
+		line uint
+	}{
+		{"UseBeforeDeclare", 1},
+		{"DuplicateDeclaration", 3},
+		{"UseBeforeDeclare", 4},
+		{"UseBeforeDeclare", 5},
+		{"UndefinedVariable", 7},
+		{"DuplicateDeclaration", 9},
+	}
+	if len(list.Errors()) != len(expected) {
+		t.Fatalf("expected %d errors, got %d:\n%s", len(expected), len(list.Errors()), list)
+	}
+	for i, err := range list.Errors() {
+		if kind := Kind(err); kind != expected[i].kind || errorToken(err).Line != expected[i].line {
+			t.Errorf("expected %s on line %d, got %s on line %d", expected[i].kind, expected[i].line, kind, errorToken(err).Line)
+		}
+	}
+}
+
+func TestResolveUnusedLocals(t *testing.T) {
+	input := "" +
+		"var global = 1;\n" +
+		"func f(unusedParam) {\n" +
+		"    var used = 1;\n" +
+		"    var assigned = 2;\n" +
+		"    assigned = used;\n" +
+		"    func helper() {}\n" +
+		"}\n"
+
+	_, r, err := resolveSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", r.Warnings)
+	}
+	if unused, ok := r.Warnings[0].(UnusedVariable); !ok || unused.Lexeme != "assigned" {
+		t.Errorf("expected 'assigned' to be unused, got %v", r.Warnings[0])
+	}
+}
+
+func TestResolveSlots(t *testing.T) {
+	input := "" +
+		"var g = 0;\n" +
+		"func f(a, b) {\n" +
+		"    var c = 1;\n" +
+		"    if a {\n" +
+		"        var d = c;\n" +
+		"        return func() { return b + d + g; };\n" +
+		"    }\n" +
+		"}\n"
+
+	program, _, err := resolveSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]local{
+		"a": {true, 0, 0},
+		"c": {true, 1, 2},
+		"b": {true, 2, 1},
+		"d": {true, 1, 0},
+		"g": {true, -1, 0},
+	}
+	Inspect(program, func(node Node) bool {
+		if variable, ok := node.(Variable); ok {
+			name := variable.identifier.Lexeme
+			if *variable.local != expected[name] {
+				t.Errorf("expected '%s' to resolve to %+v, got %+v", name, expected[name], *variable.local)
+			}
+		}
+		return true
+	})
+}
+
+func TestResolveLaterLocals(t *testing.T) {
+	input := "" +
+		"func f() {\n" +
+		"    var g = n => n == 0 or g(n - 1);\n" +
+		"    return g(3);\n" +
+		"}\n" +
+		"func outer() {\n" +
+		"    func isEven(n) { if n == 0 { return true; } return isOdd(n - 1); }\n" +
+		"    func isOdd(n) { if n == 0 { return false; } return isEven(n - 1); }\n" +
+		"    return isEven(4);\n" +
+		"}\n"
+
+	program, r, err := resolveSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings) != 0 {
+		t.Errorf("expected the locals used by the functions declared before them to be used, got %v", r.Warnings)
+	}
+
+	// Function bodies can use the locals declared after them in the scopes around them, since they run later
+	expected := map[uint]map[string]local{
+		2: {"g": {true, 1, 0}, "n": {true, 0, 0}},
+		6: {"isOdd": {true, 1, 1}, "n": {true, 0, 0}},
+		7: {"isEven": {true, 1, 0}, "n": {true, 0, 0}},
+	}
+	Inspect(program, func(node Node) bool {
+		if variable, ok := node.(Variable); ok {
+			name := variable.identifier.Lexeme
+			if want, found := expected[variable.identifier.Line][name]; found && *variable.local != want {
+				t.Errorf("expected '%s' on line %d to resolve to %+v, got %+v", name, variable.identifier.Line, want, *variable.local)
+			}
+		}
+		return true
+	})
+
+	for _, vm := range []bool{false, true} {
+		intptr := NewInterpreter()
+		if vm {
+			intptr = NewInterpreter(WithVM())
+		}
+		out := strings.Builder{}
+		intptr.HookLogOut(&out)
+		if err := intptr.Interpret(input + "print f();\nprint outer();\n"); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != "true\ntrue\n" {
+			t.Errorf("expected true twice, got %q", out.String())
+		}
+	}
+}
+
+func TestInterpretLexicalScope(t *testing.T) {
+	intptr := NewInterpreter()
+	out := strings.Builder{}
+	intptr.HookLogOut(&out)
+	intptr.HookWarnOut(&out)
+
+	// Blocks have their own scope, and a function can't see the locals of whatever called it
+	for _, input := range []string{
+		"if true { var inner = 1; }\nprint inner;\n",
+		"func show() { return local; }\nfunc caller() { var local = 1; return show(); }\n",
+	} {
+		if err := intptr.Interpret(input); err == nil {
+			t.Errorf("expected an error for:\n%s", input)
+		}
+	}
+
+	// Globals carry over from one input to the next like they do in the REPL
+	if err := intptr.Interpret("var a = 1;\nfunc next() { return a + 1; }\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := intptr.Interpret("var a = 10;\nfor var i = 0; i < 2; i = i + 1 { var a = i; print a + next(); }\n"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "11\n12\n" {
+		t.Errorf("expected the shadowed 'a' in the loop and the global 'a' in next(), got %q", out.String())
+	}
+}
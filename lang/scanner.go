@@ -1,24 +1,44 @@
 package lang
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Scanner is how a Jlang input string gets scanned and tokenized
 type Scanner struct {
-	src     string
-	start   uint
-	current uint
-	line    uint
-	tokens  []Token
-	Fatal   error
-	Errors  []error
+	src       string
+	source    *Source
+	start     uint
+	current   uint
+	line      uint
+	lineStart uint
+	tokens    []Token
+	templates []template // The strings being interpolated into, innermost last
+	trivia    []Trivia   // The comments since the last Token, which get attached to the next one
+	Fatal     error
+	Errors    []error
+}
+
+// template is a string that's in the middle of an interpolated '${expression}'
+type template struct {
+	start Token // The StringPart that opened the interpolation
+	depth uint  // How many '{' in the expression are still open, the string resumes at the '}' when it's 0
 }
 
 // Scan takes an input string and either returns a Tokenized array or an error specifying why it's
 // an invalid input sequence to be scanned.
 func (scan *Scanner) Scan(input string) ([]Token, error) {
-	scan.src = input
+	return scan.ScanSource(&Source{Text: input})
+}
+
+// ScanSource is Scan for a named Source, every scanned Token will point back into it.
+func (scan *Scanner) ScanSource(source *Source) ([]Token, error) {
+	scan.source = source
+	scan.src = source.Text
 	scan.flush()
 
 	for !scan.isAtEnd() {
@@ -29,16 +49,16 @@ func (scan *Scanner) Scan(input string) ([]Token, error) {
 			return nil, scan.Fatal
 		}
 	}
+	if len(scan.templates) > 0 {
+		return nil, UnclosedString{scan.templates[len(scan.templates)-1].start, '}'}
+	}
 
 	return scan.tokens, nil
 }
 
 func (scan *Scanner) scanToken() {
 	if scan.isNumeric() {
-		for scan.isNumeric() || scan.src[scan.current] == '.' {
-			scan.current++
-		}
-		scan.addToken(Number)
+		scan.number()
 		return
 	}
 	val := scan.advance()
@@ -46,18 +66,32 @@ func (scan *Scanner) scanToken() {
 	case ' ':
 		break
 	case '\n':
-		scan.line++
+		scan.newline()
 	case '\t':
 		break
 	case ';':
 		scan.addToken(Semicolon)
+	case ':':
+		scan.addToken(Colon)
 	case '(':
 		scan.addToken(LeftParen)
 	case ')':
 		scan.addToken(RightParen)
 	case '{':
+		if len(scan.templates) > 0 {
+			scan.templates[len(scan.templates)-1].depth++
+		}
 		scan.addToken(LeftBrace)
 	case '}':
+		if last := len(scan.templates) - 1; last >= 0 {
+			if scan.templates[last].depth == 0 {
+				// The interpolated expression is done so the rest of the string picks up from here
+				scan.templates = scan.templates[:last]
+				scan.stringParse('"')
+				break
+			}
+			scan.templates[last].depth--
+		}
 		scan.addToken(RightBrace)
 	case '[':
 		scan.addToken(LeftBracket)
@@ -84,6 +118,8 @@ func (scan *Scanner) scanToken() {
 	case '/':
 		if scan.match('/') {
 			scan.comment()
+		} else if scan.match('*') {
+			scan.blockComment()
 		} else {
 			scan.addToken(Slash)
 		}
@@ -92,6 +128,10 @@ func (scan *Scanner) scanToken() {
 			scan.addToken(EqualEqual)
 			break
 		}
+		if scan.match('>') {
+			scan.addToken(Arrow)
+			break
+		}
 		scan.addToken(Equal)
 	case '!':
 		if scan.match('=') {
@@ -111,148 +151,327 @@ func (scan *Scanner) scanToken() {
 			break
 		}
 		scan.addToken(Less)
-	case '"':
-		scan.stringParse()
+	case '"', '`':
+		scan.stringParse(val)
 	case '%':
 		scan.addToken(Mod)
+	case '&':
+		if scan.match('&') {
+			scan.addToken(And)
+			break
+		}
+		scan.Fatal = UnknownToken{scan.token(Identifier)}
+	case '|':
+		if scan.match('|') {
+			scan.addToken(Or)
+			break
+		}
+		scan.Fatal = UnknownToken{scan.token(Identifier)}
 	default:
-		scan.multi(val)
+		if isIdentifierStart(val) {
+			scan.identifier()
+			break
+		}
+		scan.Fatal = UnknownToken{scan.token(Identifier)}
 	}
 }
 
-func (scan *Scanner) multi(val byte) {
-	if val == 'i' && scan.match('f') {
-		scan.addToken(If)
-		return
-	}
-	if val == 'e' && scan.matchStr("lse") {
-		scan.addToken(Else)
-		return
+// number scans a Number literal, which is an int i.e '42', '1_000_000', '0xFF' or '0b1010', or a float
+// i.e '3.14' or '1e-9'. Everything that's stuck to the number is scanned with it so that something like
+// '1.2.3' or '12px' is a malformed number instead of a number followed by something else.
+func (scan *Scanner) number() {
+	decimal := !(scan.peek('0') && strings.ContainsRune("xXbB", scan.peekNext()))
+	for !scan.isAtEnd() {
+		if scan.isIdentifier() || (scan.peek('.') && unicode.IsDigit(scan.peekNext())) {
+			scan.advance()
+			continue
+		}
+		// The sign of an exponent i.e '1e-9'
+		if prev := scan.src[scan.current-1]; decimal && (prev == 'e' || prev == 'E') && (scan.peek('-') || scan.peek('+')) {
+			scan.advance()
+			continue
+		}
+		break
 	}
-	if val == 't' && scan.matchStr("rue") {
-		scan.addToken(True)
+
+	token := scan.token(Number)
+	value, err := parseNumber(token.Lexeme)
+	if err != nil {
+		scan.Fatal = InvalidNumber{token, err.Error()}
 		return
 	}
+	token.value = value
+	scan.emit(token)
+}
 
-	if val == 'f' {
-		if scan.matchStr("or") {
-			scan.addToken(For)
-			return
+// parseNumber is the int or float64 value of a Number lexeme, or why it's malformed
+func parseNumber(lexeme string) (Value, error) {
+	digits, base := lexeme, 10
+	if len(lexeme) > 1 && lexeme[0] == '0' {
+		switch lexeme[1] {
+		case 'x', 'X':
+			digits, base = lexeme[2:], 16
+		case 'b', 'B':
+			digits, base = lexeme[2:], 2
 		}
-		if scan.matchStr("unc") {
-			scan.addToken(Function)
-			return
+	}
+
+	// Underscores are only allowed between two digits i.e '1_000' but not '1__000', '_1' or '1_'
+	isDigit := func(i int) bool {
+		if i < 0 || i >= len(digits) {
+			return false
 		}
-		if scan.matchStr("alse") {
-			scan.addToken(False)
-			return
+		_, err := strconv.ParseUint(digits[i:i+1], base, 8)
+		return err == nil
+	}
+	for i := range digits {
+		if digits[i] == '_' && !(isDigit(i-1) && isDigit(i+1)) {
+			return Value{}, fmt.Errorf("'_' must separate digits")
 		}
 	}
+	digits = strings.ReplaceAll(digits, "_", "")
 
-	if val == 'c' && scan.matchStr("lass") {
-		scan.addToken(Class)
-		return
+	if base == 10 && strings.ContainsAny(digits, ".eE") {
+		val, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return Value{}, numberError(err)
+		}
+		return numberValue(val), nil
 	}
-
-	if val == 'p' && scan.matchStr("rint") {
-		scan.addToken(Print)
-		return
+	val, err := strconv.ParseInt(digits, base, 0)
+	if err != nil {
+		return Value{}, numberError(err)
 	}
-	if val == 'v' && scan.matchStr("ar") {
-		scan.addToken(Var)
-		return
+	return intValue(int(val)), nil
+}
+
+// numberError is the reason strconv couldn't parse a number
+func numberError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return fmt.Errorf("number is out of range")
 	}
-	if val == 'r' && scan.matchStr("eturn") {
-		scan.addToken(Return)
-		return
+	return fmt.Errorf("not a valid number")
+}
+
+// identifier scans the longest run of identifier characters, then it's a keyword if it's in the keywords table
+func (scan *Scanner) identifier() {
+	for !scan.isAtEnd() && scan.isIdentifier() {
+		scan.advance()
 	}
-	if val == 'w' && scan.matchStr("hile") {
-		scan.addToken(While)
+	if keyword, found := keywords[scan.src[scan.start:scan.current]]; found {
+		scan.addToken(keyword)
 		return
 	}
+	scan.addToken(Identifier)
+}
 
-	if val == 'n' && scan.matchStr("il") {
-		scan.addToken(Nil)
-		return
+// stringParse scans a string up to the closing quote, which is either '"' or '`'.
+// A '"' string decodes escape sequences i.e '\n' while a '`' raw string is kept exactly as written.
+// Both can span multiple lines.
+// A '"' string can also interpolate expressions i.e "area: ${rect.area()}". The part of the string before the
+// '${' becomes a StringPart token, then the expression is scanned like normal until its closing '}', which is
+// where stringParse() picks the rest of the string up again.
+func (scan *Scanner) stringParse(quote rune) {
+	line, lineStart := scan.line, scan.lineStart
+	lexeme := strings.Builder{}
+	for !scan.isAtEnd() {
+		val := scan.advance()
+		switch {
+		case val == quote:
+			// The lexeme is *inside* the quotation marks "X____________Y" X=start Y=current while the span keeps them
+			token := scan.tokenFrom(String, scan.start, line, lineStart)
+			token.Lexeme = lexeme.String()
+			scan.emit(token)
+			return
+		case val == '\n':
+			scan.newline()
+		case val == '$' && quote == '"' && scan.match('{'):
+			token := scan.tokenFrom(StringPart, scan.start, line, lineStart)
+			token.Lexeme = lexeme.String()
+			scan.emit(token)
+			scan.templates = append(scan.templates, template{token, 0})
+			return
+		case val == '\\' && quote == '"':
+			escaped, err := scan.escape()
+			if err != nil {
+				scan.Fatal = err
+				return
+			}
+			val = escaped
+		}
+		lexeme.WriteRune(val)
 	}
 
-	scan.identifier()
+	scan.Fatal = UnclosedString{scan.tokenFrom(String, scan.start, line, lineStart), quote}
 }
 
-func (scan *Scanner) identifier() {
-	for scan.isIdentifier() {
-		scan.current++
-	}
-	scan.addToken(Identifier)
-	//scan.Fatal(UnknownToken{string(scan.src[scan.current]), scan.line})
+// escapes are the single character escape sequences and what they decode to
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'$':  '$',
+	'\\': '\\',
 }
 
-func (scan *Scanner) stringParse() {
-	err := scan.seek('"')
-	if err != nil {
-		scan.Fatal = UnclosedString{scan.line}
-		return
+// escape decodes the escape sequence after a '\' in a string, either one of escapes or a '\u{XXXX}' code point
+func (scan *Scanner) escape() (rune, error) {
+	start := scan.current - 1
+	if scan.isAtEnd() {
+		return 0, InvalidEscape{scan.tokenFrom(String, start, scan.line, scan.lineStart)}
 	}
 
-	// Temporarily moves the addToken() consume to *inside* the quotation marks "X____________Y" X=start Y=current
-	scan.start++
-	scan.current--
-	scan.addToken(String)
-	scan.current++
+	val := scan.advance()
+	if decoded, found := escapes[val]; found {
+		return decoded, nil
+	}
+	if val == 'u' && scan.match('{') {
+		digits := scan.current
+		for !scan.isAtEnd() && !scan.peek('}') && !scan.peek('"') {
+			scan.advance()
+		}
+		hex := scan.src[digits:scan.current]
+		if scan.match('}') {
+			if code, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) <= 6 && utf8.ValidRune(rune(code)) {
+				return rune(code), nil
+			}
+		}
+	}
+	return 0, InvalidEscape{scan.tokenFrom(String, start, scan.line, scan.lineStart)}
 }
 
+// comment skips to the end of the line, leaving the '\n' to scanToken() so line tracking stays correct
 func (scan *Scanner) comment() {
-	for true {
-		val := scan.advance()
-		if val == '\n' {
-			break
+	for !scan.isAtEnd() && !scan.peek('\n') {
+		scan.advance()
+	}
+	scan.addTrivia(scan.line, false)
+}
+
+// blockComment skips to the closing '*/', block comments can be nested i.e '/* outer /* inner */ still outer */'
+func (scan *Scanner) blockComment() {
+	line := scan.line
+	depth := 1
+	for depth > 0 {
+		if scan.isAtEnd() {
+			scan.Fatal = UnclosedComment{scan.tokenFrom(Slash, scan.start, line, scan.lineStart)}
+			return
+		}
+		switch val := scan.advance(); {
+		case val == '\n':
+			scan.newline()
+		case val == '/' && scan.match('*'):
+			depth++
+		case val == '*' && scan.match('/'):
+			depth--
 		}
 	}
+	scan.addTrivia(line, true)
+}
+
+// addTrivia keeps the comment that was just scanned. A comment on the same line after a Token trails it i.e
+// 'x = 1; // note', otherwise it leads the next Token i.e a doc comment above a declaration.
+func (scan *Scanner) addTrivia(line uint, block bool) {
+	trivia := Trivia{scan.src[scan.start:scan.current], line, scan.start, scan.current, block}
+	if last := len(scan.tokens) - 1; last >= 0 && len(scan.trivia) == 0 {
+		if between := scan.src[scan.tokens[last].End:scan.start]; !strings.ContainsRune(between, '\n') {
+			scan.tokens[last].Trailing = append(scan.tokens[last].Trailing, trivia)
+			return
+		}
+	}
+	scan.trivia = append(scan.trivia, trivia)
+}
+
+// emit adds the Token along with the comments that lead it
+func (scan *Scanner) emit(token Token) {
+	token.Leading = scan.trivia
+	scan.trivia = nil
+	scan.tokens = append(scan.tokens, token)
 }
 
 func (scan *Scanner) addToken(tokenType int) {
-	scan.tokens = append(scan.tokens, Token{scan.src[scan.start:scan.current], tokenType, scan.line})
+	scan.emit(scan.token(tokenType))
 }
 
-func (scan *Scanner) advance() byte {
-	val := scan.src[scan.current]
-	scan.current++
-	return val
+// token builds a Token of tokenType spanning from start to current
+func (scan *Scanner) token(tokenType int) Token {
+	return scan.tokenFrom(tokenType, scan.start, scan.line, scan.lineStart)
 }
 
-func (scan *Scanner) peek(expected byte) bool {
-	if scan.isAtEnd() {
-		return false
+// tokenFrom builds a Token of tokenType spanning from start to current, for tokens that didn't begin on the current line
+func (scan *Scanner) tokenFrom(tokenType int, start uint, line uint, lineStart uint) Token {
+	return Token{
+		Lexeme: scan.src[start:scan.current],
+		Type:   tokenType,
+		Line:   line,
+		Column: uint(utf8.RuneCountInString(scan.src[lineStart:start])) + 1,
+		Start:  start,
+		End:    scan.current,
+		src:    scan.source,
 	}
+}
 
-	return scan.src[scan.current] == expected
+// eof is the EOF Token positioned at the very end of the scanned source
+func (scan *Scanner) eof() Token {
+	scan.start = uint(len(scan.src))
+	scan.current = scan.start
+	token := scan.token(EOF)
+	token.Lexeme = "EOF"
+	// Comments at the very end of the source have nothing after them to lead
+	token.Leading = scan.trivia
+	return token
 }
 
-func (scan *Scanner) match(expected byte) bool {
-	if scan.peek(expected) {
-		scan.current++
-		return true
+func (scan *Scanner) newline() {
+	scan.line++
+	scan.lineStart = scan.current
+}
+
+// advance consumes the next rune of the source, which can be more than one byte
+func (scan *Scanner) advance() rune {
+	val, size := utf8.DecodeRuneInString(scan.src[scan.current:])
+	scan.current += uint(size)
+	return val
+}
+
+// next is the upcoming rune without consuming it, or utf8.RuneError at the end of the source
+func (scan *Scanner) next() rune {
+	if scan.isAtEnd() {
+		return utf8.RuneError
 	}
-	return false
+	val, _ := utf8.DecodeRuneInString(scan.src[scan.current:])
+	return val
 }
 
-func (scan *Scanner) matchStr(expected string) bool {
-	for _, expChar := range expected {
-		if !scan.match(byte(expChar)) {
-			return false
-		}
+// peekNext is the rune after the upcoming one without consuming anything, or utf8.RuneError if there isn't one
+func (scan *Scanner) peekNext() rune {
+	if scan.isAtEnd() {
+		return utf8.RuneError
 	}
-	return true
+	_, size := utf8.DecodeRuneInString(scan.src[scan.current:])
+	if scan.current+uint(size) >= uint(len(scan.src)) {
+		return utf8.RuneError
+	}
+	val, _ := utf8.DecodeRuneInString(scan.src[scan.current+uint(size):])
+	return val
 }
 
-func (scan *Scanner) seek(expected byte) error {
-	for !scan.isAtEnd() {
-		if a := scan.advance(); a == expected {
-			return nil
-		}
+func (scan *Scanner) peek(expected rune) bool {
+	if scan.isAtEnd() {
+		return false
 	}
 
-	return InternalError{10, "scanner.seek() reached end of file"}
+	return scan.next() == expected
+}
+
+func (scan *Scanner) match(expected rune) bool {
+	if scan.peek(expected) {
+		scan.advance()
+		return true
+	}
+	return false
 }
 
 func (scan *Scanner) isAtEnd() bool {
@@ -260,29 +479,29 @@ func (scan *Scanner) isAtEnd() bool {
 }
 
 func (scan *Scanner) isNumeric() bool {
-	val := scan.src[scan.current]
-	return unicode.IsDigit(rune(val))
+	return !scan.isAtEnd() && unicode.IsDigit(scan.next())
 }
 
+// isIdentifier is whether the next rune can be part of an identifier, which is anything an identifier can
+// start with, and digits
 func (scan *Scanner) isIdentifier() bool {
-	val := scan.src[int(scan.current)]
-	if val >= 48 && val <= 56 {
-		return true
-	}
-	if val >= 65 && val <= 90 {
-		return true
-	}
-	if val >= 97 && val <= 122 {
-		return true
-	}
-	return false
+	val := scan.next()
+	return isIdentifierStart(val) || unicode.IsDigit(val)
+}
+
+// isIdentifierStart is whether an identifier can start with val, which is any letter or '_' i.e '_count', 'café' or 'π'
+func isIdentifierStart(val rune) bool {
+	return val == '_' || unicode.IsLetter(val)
 }
 
 func (scan *Scanner) flush() {
 	scan.Errors = make([]error, 0)
 	scan.tokens = make([]Token, 0)
+	scan.templates = nil
+	scan.trivia = nil
 	scan.start = 0
 	scan.current = 0
 	scan.line = 1
+	scan.lineStart = 0
 	scan.Fatal = nil
 }
@@ -16,103 +16,103 @@ func TestScanClass(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		Token{"class", Class, 1},
-		Token{"Test", Identifier, 1},
-		Token{"{", LeftBrace, 1},
-		Token{"var", Var, 2},
-		Token{"empty", Identifier, 2},
-		Token{";", Semicolon, 2},
-		Token{"var", Var, 3},
-		Token{"name", Identifier, 3},
-		Token{"=", Equal, 3},
-		Token{"test_class", String, 3},
-		Token{";", Semicolon, 3},
-		Token{"var", Var, 4},
-		Token{"id", Identifier, 4},
-		Token{"=", Equal, 4},
-		Token{"1", Number, 4},
-		Token{";", Semicolon, 4},
-		Token{"func", Function, 6},
-		Token{"Test", Identifier, 6},
-		Token{"(", LeftParen, 6},
-		Token{")", RightParen, 6},
-		Token{"{", LeftBrace, 6},
-		Token{"print", Print, 7},
-		Token{"init", String, 7},
-		Token{";", Semicolon, 7},
-		Token{"}", RightBrace, 8},
-		Token{"func", Function, 10},
-		Token{"getName", Identifier, 10},
-		Token{"(", LeftParen, 10},
-		Token{")", RightParen, 10},
-		Token{"{", LeftBrace, 10},
-		Token{"return", Return, 11},
-		Token{"this", Identifier, 11},
-		Token{".", Dot, 11},
-		Token{"name", Identifier, 11},
-		Token{"+", Plus, 11},
-		Token{"-", String, 11},
-		Token{"+", Plus, 11},
-		Token{"this", Identifier, 11},
-		Token{".", Dot, 11},
-		Token{"id", Identifier, 11},
-		Token{";", Semicolon, 11},
-		Token{"}", RightBrace, 12},
-		Token{"func", Function, 14},
-		Token{"setName", Identifier, 14},
-		Token{"(", LeftParen, 14},
-		Token{"name", Identifier, 14},
-		Token{")", RightParen, 14},
-		Token{"{", LeftBrace, 14},
-		Token{"print", Print, 15},
-		Token{"setting name to: ", String, 15},
-		Token{"+", Plus, 15},
-		Token{"name", Identifier, 15},
-		Token{";", Semicolon, 15},
-		Token{"this", Identifier, 16},
-		Token{".", Dot, 16},
-		Token{"name", Identifier, 16},
-		Token{"=", Equal, 16},
-		Token{"name", Identifier, 16},
-		Token{";", Semicolon, 16},
-		Token{"}", RightBrace, 17},
-		Token{"}", RightBrace, 18},
-		Token{"var", Var, 20},
-		Token{"test", Identifier, 20},
-		Token{"=", Equal, 20},
-		Token{"Test", Identifier, 20},
-		Token{"(", LeftParen, 20},
-		Token{")", RightParen, 20},
-		Token{";", Semicolon, 20},
-		Token{"print", Print, 21},
-		Token{"test", Identifier, 21},
-		Token{";", Semicolon, 21},
-		Token{"print", Print, 22},
-		Token{"test", Identifier, 22},
-		Token{".", Dot, 22},
-		Token{"id", Identifier, 22},
-		Token{";", Semicolon, 22},
-		Token{"print", Print, 23},
-		Token{"test", Identifier, 23},
-		Token{".", Dot, 23},
-		Token{"getName", Identifier, 23},
-		Token{"(", LeftParen, 23},
-		Token{")", RightParen, 23},
-		Token{";", Semicolon, 23},
-		Token{"test", Identifier, 24},
-		Token{".", Dot, 24},
-		Token{"setName", Identifier, 24},
-		Token{"(", LeftParen, 24},
-		Token{"PauseChamp", String, 24},
-		Token{")", RightParen, 24},
-		Token{";", Semicolon, 24},
-		Token{"print", Print, 25},
-		Token{"test", Identifier, 25},
-		Token{".", Dot, 25},
-		Token{"getName", Identifier, 25},
-		Token{"(", LeftParen, 25},
-		Token{")", RightParen, 25},
-		Token{";", Semicolon, 25},
+		Token{Lexeme: "class", Type: Class, Line: 1},
+		Token{Lexeme: "Test", Type: Identifier, Line: 1},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 1},
+		Token{Lexeme: "var", Type: Var, Line: 2},
+		Token{Lexeme: "empty", Type: Identifier, Line: 2},
+		Token{Lexeme: ";", Type: Semicolon, Line: 2},
+		Token{Lexeme: "var", Type: Var, Line: 3},
+		Token{Lexeme: "name", Type: Identifier, Line: 3},
+		Token{Lexeme: "=", Type: Equal, Line: 3},
+		Token{Lexeme: "test_class", Type: String, Line: 3},
+		Token{Lexeme: ";", Type: Semicolon, Line: 3},
+		Token{Lexeme: "var", Type: Var, Line: 4},
+		Token{Lexeme: "id", Type: Identifier, Line: 4},
+		Token{Lexeme: "=", Type: Equal, Line: 4},
+		Token{Lexeme: "1", Type: Number, Line: 4},
+		Token{Lexeme: ";", Type: Semicolon, Line: 4},
+		Token{Lexeme: "func", Type: Function, Line: 6},
+		Token{Lexeme: "Test", Type: Identifier, Line: 6},
+		Token{Lexeme: "(", Type: LeftParen, Line: 6},
+		Token{Lexeme: ")", Type: RightParen, Line: 6},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 6},
+		Token{Lexeme: "print", Type: Print, Line: 7},
+		Token{Lexeme: "init", Type: String, Line: 7},
+		Token{Lexeme: ";", Type: Semicolon, Line: 7},
+		Token{Lexeme: "}", Type: RightBrace, Line: 8},
+		Token{Lexeme: "func", Type: Function, Line: 10},
+		Token{Lexeme: "getName", Type: Identifier, Line: 10},
+		Token{Lexeme: "(", Type: LeftParen, Line: 10},
+		Token{Lexeme: ")", Type: RightParen, Line: 10},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 10},
+		Token{Lexeme: "return", Type: Return, Line: 11},
+		Token{Lexeme: "this", Type: Identifier, Line: 11},
+		Token{Lexeme: ".", Type: Dot, Line: 11},
+		Token{Lexeme: "name", Type: Identifier, Line: 11},
+		Token{Lexeme: "+", Type: Plus, Line: 11},
+		Token{Lexeme: "-", Type: String, Line: 11},
+		Token{Lexeme: "+", Type: Plus, Line: 11},
+		Token{Lexeme: "this", Type: Identifier, Line: 11},
+		Token{Lexeme: ".", Type: Dot, Line: 11},
+		Token{Lexeme: "id", Type: Identifier, Line: 11},
+		Token{Lexeme: ";", Type: Semicolon, Line: 11},
+		Token{Lexeme: "}", Type: RightBrace, Line: 12},
+		Token{Lexeme: "func", Type: Function, Line: 14},
+		Token{Lexeme: "setName", Type: Identifier, Line: 14},
+		Token{Lexeme: "(", Type: LeftParen, Line: 14},
+		Token{Lexeme: "name", Type: Identifier, Line: 14},
+		Token{Lexeme: ")", Type: RightParen, Line: 14},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 14},
+		Token{Lexeme: "print", Type: Print, Line: 15},
+		Token{Lexeme: "setting name to: ", Type: String, Line: 15},
+		Token{Lexeme: "+", Type: Plus, Line: 15},
+		Token{Lexeme: "name", Type: Identifier, Line: 15},
+		Token{Lexeme: ";", Type: Semicolon, Line: 15},
+		Token{Lexeme: "this", Type: Identifier, Line: 16},
+		Token{Lexeme: ".", Type: Dot, Line: 16},
+		Token{Lexeme: "name", Type: Identifier, Line: 16},
+		Token{Lexeme: "=", Type: Equal, Line: 16},
+		Token{Lexeme: "name", Type: Identifier, Line: 16},
+		Token{Lexeme: ";", Type: Semicolon, Line: 16},
+		Token{Lexeme: "}", Type: RightBrace, Line: 17},
+		Token{Lexeme: "}", Type: RightBrace, Line: 18},
+		Token{Lexeme: "var", Type: Var, Line: 20},
+		Token{Lexeme: "test", Type: Identifier, Line: 20},
+		Token{Lexeme: "=", Type: Equal, Line: 20},
+		Token{Lexeme: "Test", Type: Identifier, Line: 20},
+		Token{Lexeme: "(", Type: LeftParen, Line: 20},
+		Token{Lexeme: ")", Type: RightParen, Line: 20},
+		Token{Lexeme: ";", Type: Semicolon, Line: 20},
+		Token{Lexeme: "print", Type: Print, Line: 21},
+		Token{Lexeme: "test", Type: Identifier, Line: 21},
+		Token{Lexeme: ";", Type: Semicolon, Line: 21},
+		Token{Lexeme: "print", Type: Print, Line: 22},
+		Token{Lexeme: "test", Type: Identifier, Line: 22},
+		Token{Lexeme: ".", Type: Dot, Line: 22},
+		Token{Lexeme: "id", Type: Identifier, Line: 22},
+		Token{Lexeme: ";", Type: Semicolon, Line: 22},
+		Token{Lexeme: "print", Type: Print, Line: 23},
+		Token{Lexeme: "test", Type: Identifier, Line: 23},
+		Token{Lexeme: ".", Type: Dot, Line: 23},
+		Token{Lexeme: "getName", Type: Identifier, Line: 23},
+		Token{Lexeme: "(", Type: LeftParen, Line: 23},
+		Token{Lexeme: ")", Type: RightParen, Line: 23},
+		Token{Lexeme: ";", Type: Semicolon, Line: 23},
+		Token{Lexeme: "test", Type: Identifier, Line: 24},
+		Token{Lexeme: ".", Type: Dot, Line: 24},
+		Token{Lexeme: "setName", Type: Identifier, Line: 24},
+		Token{Lexeme: "(", Type: LeftParen, Line: 24},
+		Token{Lexeme: "PauseChamp", Type: String, Line: 24},
+		Token{Lexeme: ")", Type: RightParen, Line: 24},
+		Token{Lexeme: ";", Type: Semicolon, Line: 24},
+		Token{Lexeme: "print", Type: Print, Line: 25},
+		Token{Lexeme: "test", Type: Identifier, Line: 25},
+		Token{Lexeme: ".", Type: Dot, Line: 25},
+		Token{Lexeme: "getName", Type: Identifier, Line: 25},
+		Token{Lexeme: "(", Type: LeftParen, Line: 25},
+		Token{Lexeme: ")", Type: RightParen, Line: 25},
+		Token{Lexeme: ";", Type: Semicolon, Line: 25},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -132,52 +132,52 @@ func TestScanFunc(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		Token{"func", Function, 1},
-		Token{"test", Identifier, 1},
-		Token{"(", LeftParen, 1},
-		Token{")", RightParen, 1},
-		Token{"{", LeftBrace, 1},
-		Token{"return", Return, 2},
-		Token{"test", String, 2},
-		Token{";", Semicolon, 2},
-		Token{"}", RightBrace, 3},
-		Token{"func", Function, 5},
-		Token{"negate", Identifier, 5},
-		Token{"(", LeftParen, 5},
-		Token{"x", Identifier, 5},
-		Token{")", RightParen, 5},
-		Token{"{", LeftBrace, 5},
-		Token{"return", Return, 6},
-		Token{"-", Minus, 6},
-		Token{"x", Identifier, 6},
-		Token{";", Semicolon, 6},
-		Token{"}", RightBrace, 7},
-		Token{"print", Print, 9},
-		Token{"test", Identifier, 9},
-		Token{"(", LeftParen, 9},
-		Token{")", RightParen, 9},
-		Token{";", Semicolon, 9},
-		Token{"print", Print, 10},
-		Token{"test2: ", String, 10},
-		Token{"+", Plus, 10},
-		Token{"test", Identifier, 10},
-		Token{"(", LeftParen, 10},
-		Token{")", RightParen, 10},
-		Token{";", Semicolon, 10},
-		Token{"print", Print, 11},
-		Token{"negate", Identifier, 11},
-		Token{"(", LeftParen, 11},
-		Token{"5", Number, 11},
-		Token{")", RightParen, 11},
-		Token{";", Semicolon, 11},
-		Token{"print", Print, 13},
-		Token{"negate", Identifier, 13},
-		Token{"(", LeftParen, 13},
-		Token{"5", Number, 13},
-		Token{",", Comma, 13},
-		Token{"3", Number, 13},
-		Token{")", RightParen, 13},
-		Token{";", Semicolon, 13},
+		Token{Lexeme: "func", Type: Function, Line: 1},
+		Token{Lexeme: "test", Type: Identifier, Line: 1},
+		Token{Lexeme: "(", Type: LeftParen, Line: 1},
+		Token{Lexeme: ")", Type: RightParen, Line: 1},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 1},
+		Token{Lexeme: "return", Type: Return, Line: 2},
+		Token{Lexeme: "test", Type: String, Line: 2},
+		Token{Lexeme: ";", Type: Semicolon, Line: 2},
+		Token{Lexeme: "}", Type: RightBrace, Line: 3},
+		Token{Lexeme: "func", Type: Function, Line: 5},
+		Token{Lexeme: "negate", Type: Identifier, Line: 5},
+		Token{Lexeme: "(", Type: LeftParen, Line: 5},
+		Token{Lexeme: "x", Type: Identifier, Line: 5},
+		Token{Lexeme: ")", Type: RightParen, Line: 5},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 5},
+		Token{Lexeme: "return", Type: Return, Line: 6},
+		Token{Lexeme: "-", Type: Minus, Line: 6},
+		Token{Lexeme: "x", Type: Identifier, Line: 6},
+		Token{Lexeme: ";", Type: Semicolon, Line: 6},
+		Token{Lexeme: "}", Type: RightBrace, Line: 7},
+		Token{Lexeme: "print", Type: Print, Line: 9},
+		Token{Lexeme: "test", Type: Identifier, Line: 9},
+		Token{Lexeme: "(", Type: LeftParen, Line: 9},
+		Token{Lexeme: ")", Type: RightParen, Line: 9},
+		Token{Lexeme: ";", Type: Semicolon, Line: 9},
+		Token{Lexeme: "print", Type: Print, Line: 10},
+		Token{Lexeme: "test2: ", Type: String, Line: 10},
+		Token{Lexeme: "+", Type: Plus, Line: 10},
+		Token{Lexeme: "test", Type: Identifier, Line: 10},
+		Token{Lexeme: "(", Type: LeftParen, Line: 10},
+		Token{Lexeme: ")", Type: RightParen, Line: 10},
+		Token{Lexeme: ";", Type: Semicolon, Line: 10},
+		Token{Lexeme: "print", Type: Print, Line: 11},
+		Token{Lexeme: "negate", Type: Identifier, Line: 11},
+		Token{Lexeme: "(", Type: LeftParen, Line: 11},
+		Token{Lexeme: "5", Type: Number, Line: 11},
+		Token{Lexeme: ")", Type: RightParen, Line: 11},
+		Token{Lexeme: ";", Type: Semicolon, Line: 11},
+		Token{Lexeme: "print", Type: Print, Line: 14},
+		Token{Lexeme: "negate", Type: Identifier, Line: 14},
+		Token{Lexeme: "(", Type: LeftParen, Line: 14},
+		Token{Lexeme: "5", Type: Number, Line: 14},
+		Token{Lexeme: ",", Type: Comma, Line: 14},
+		Token{Lexeme: "3", Type: Number, Line: 14},
+		Token{Lexeme: ")", Type: RightParen, Line: 14},
+		Token{Lexeme: ";", Type: Semicolon, Line: 14},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -197,35 +197,35 @@ func TestScanDoubleOperator(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		{"var", Var, 1},
-		{"val", Identifier, 1},
-		{"=", Equal, 1},
-		{"1", Number, 1},
-		{";", Semicolon, 1},
-		{"val", Identifier, 2},
-		{">", Greater, 2},
-		{"1", Number, 2},
-		{";", Semicolon, 2},
-		{"val", Identifier, 3},
-		{">=", GreaterEqual, 3},
-		{"1", Number, 3},
-		{";", Semicolon, 3},
-		{"val", Identifier, 4},
-		{"!=", BangEqual, 4},
-		{"1", Number, 4},
-		{";", Semicolon, 4},
-		{"val", Identifier, 5},
-		{">", Greater, 5},
-		{"1", Number, 5},
-		{";", Semicolon, 5},
-		{"val", Identifier, 6},
-		{">=", GreaterEqual, 6},
-		{"1", Number, 6},
-		{";", Semicolon, 6},
-		{"val", Identifier, 7},
-		{"==", EqualEqual, 7},
-		{"1", Number, 7},
-		{";", Semicolon, 7},
+		{Lexeme: "var", Type: Var, Line: 1},
+		{Lexeme: "val", Type: Identifier, Line: 1},
+		{Lexeme: "=", Type: Equal, Line: 1},
+		{Lexeme: "1", Type: Number, Line: 1},
+		{Lexeme: ";", Type: Semicolon, Line: 1},
+		{Lexeme: "val", Type: Identifier, Line: 2},
+		{Lexeme: ">", Type: Greater, Line: 2},
+		{Lexeme: "1", Type: Number, Line: 2},
+		{Lexeme: ";", Type: Semicolon, Line: 2},
+		{Lexeme: "val", Type: Identifier, Line: 3},
+		{Lexeme: ">=", Type: GreaterEqual, Line: 3},
+		{Lexeme: "1", Type: Number, Line: 3},
+		{Lexeme: ";", Type: Semicolon, Line: 3},
+		{Lexeme: "val", Type: Identifier, Line: 4},
+		{Lexeme: "!=", Type: BangEqual, Line: 4},
+		{Lexeme: "1", Type: Number, Line: 4},
+		{Lexeme: ";", Type: Semicolon, Line: 4},
+		{Lexeme: "val", Type: Identifier, Line: 5},
+		{Lexeme: ">", Type: Greater, Line: 5},
+		{Lexeme: "1", Type: Number, Line: 5},
+		{Lexeme: ";", Type: Semicolon, Line: 5},
+		{Lexeme: "val", Type: Identifier, Line: 6},
+		{Lexeme: ">=", Type: GreaterEqual, Line: 6},
+		{Lexeme: "1", Type: Number, Line: 6},
+		{Lexeme: ";", Type: Semicolon, Line: 6},
+		{Lexeme: "val", Type: Identifier, Line: 7},
+		{Lexeme: "==", Type: EqualEqual, Line: 7},
+		{Lexeme: "1", Type: Number, Line: 7},
+		{Lexeme: ";", Type: Semicolon, Line: 7},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -247,48 +247,48 @@ func TestScanFor(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		Token{"for", For, 1},
-		Token{"var", Var, 1},
-		Token{"i", Identifier, 1},
-		Token{"=", Equal, 1},
-		Token{"0", Number, 1},
-		Token{";", Semicolon, 1},
-		Token{"i", Identifier, 1},
-		Token{"<", Less, 1},
-		Token{"5", Number, 1},
-		Token{";", Semicolon, 1},
-		Token{"i", Identifier, 1},
-		Token{"=", Equal, 1},
-		Token{"i", Identifier, 1},
-		Token{"+", Plus, 1},
-		Token{"1", Number, 1},
-		Token{"{", LeftBrace, 1},
-		Token{"print", Print, 2},
-		Token{"i", Identifier, 2},
-		Token{";", Semicolon, 2},
-		Token{"}", RightBrace, 3},
-		Token{"for", For, 5},
-		Token{"var", Var, 5},
-		Token{"i", Identifier, 5},
-		Token{"=", Equal, 5},
-		Token{"5", Number, 5},
-		Token{";", Semicolon, 5},
-		Token{"i", Identifier, 5},
-		Token{">", Greater, 5},
-		Token{"0", Number, 5},
-		Token{";", Semicolon, 5},
-		Token{"i", Identifier, 5},
-		Token{"=", Equal, 5},
-		Token{"i", Identifier, 5},
-		Token{"-", Minus, 5},
-		Token{"1", Number, 5},
-		Token{"{", LeftBrace, 5},
-		Token{"print", Print, 6},
-		Token{"going back: ", String, 6},
-		Token{"+", Plus, 6},
-		Token{"i", Identifier, 6},
-		Token{";", Semicolon, 6},
-		Token{"}", RightBrace, 7},
+		Token{Lexeme: "for", Type: For, Line: 1},
+		Token{Lexeme: "var", Type: Var, Line: 1},
+		Token{Lexeme: "i", Type: Identifier, Line: 1},
+		Token{Lexeme: "=", Type: Equal, Line: 1},
+		Token{Lexeme: "0", Type: Number, Line: 1},
+		Token{Lexeme: ";", Type: Semicolon, Line: 1},
+		Token{Lexeme: "i", Type: Identifier, Line: 1},
+		Token{Lexeme: "<", Type: Less, Line: 1},
+		Token{Lexeme: "5", Type: Number, Line: 1},
+		Token{Lexeme: ";", Type: Semicolon, Line: 1},
+		Token{Lexeme: "i", Type: Identifier, Line: 1},
+		Token{Lexeme: "=", Type: Equal, Line: 1},
+		Token{Lexeme: "i", Type: Identifier, Line: 1},
+		Token{Lexeme: "+", Type: Plus, Line: 1},
+		Token{Lexeme: "1", Type: Number, Line: 1},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 1},
+		Token{Lexeme: "print", Type: Print, Line: 2},
+		Token{Lexeme: "i", Type: Identifier, Line: 2},
+		Token{Lexeme: ";", Type: Semicolon, Line: 2},
+		Token{Lexeme: "}", Type: RightBrace, Line: 3},
+		Token{Lexeme: "for", Type: For, Line: 5},
+		Token{Lexeme: "var", Type: Var, Line: 5},
+		Token{Lexeme: "i", Type: Identifier, Line: 5},
+		Token{Lexeme: "=", Type: Equal, Line: 5},
+		Token{Lexeme: "5", Type: Number, Line: 5},
+		Token{Lexeme: ";", Type: Semicolon, Line: 5},
+		Token{Lexeme: "i", Type: Identifier, Line: 5},
+		Token{Lexeme: ">", Type: Greater, Line: 5},
+		Token{Lexeme: "0", Type: Number, Line: 5},
+		Token{Lexeme: ";", Type: Semicolon, Line: 5},
+		Token{Lexeme: "i", Type: Identifier, Line: 5},
+		Token{Lexeme: "=", Type: Equal, Line: 5},
+		Token{Lexeme: "i", Type: Identifier, Line: 5},
+		Token{Lexeme: "-", Type: Minus, Line: 5},
+		Token{Lexeme: "1", Type: Number, Line: 5},
+		Token{Lexeme: "{", Type: LeftBrace, Line: 5},
+		Token{Lexeme: "print", Type: Print, Line: 6},
+		Token{Lexeme: "going back: ", Type: String, Line: 6},
+		Token{Lexeme: "+", Type: Plus, Line: 6},
+		Token{Lexeme: "i", Type: Identifier, Line: 6},
+		Token{Lexeme: ";", Type: Semicolon, Line: 6},
+		Token{Lexeme: "}", Type: RightBrace, Line: 7},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -308,14 +308,14 @@ func TestScanArithmetic(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		{"5", Number, 1},
-		{"+", Plus, 1},
-		{"4", Number, 1},
-		{"-", Minus, 1},
-		{"3", Number, 1},
-		{"==", EqualEqual, 1},
-		{"6", Number, 1},
-		{";", Semicolon, 1},
+		{Lexeme: "5", Type: Number, Line: 1},
+		{Lexeme: "+", Type: Plus, Line: 1},
+		{Lexeme: "4", Type: Number, Line: 1},
+		{Lexeme: "-", Type: Minus, Line: 1},
+		{Lexeme: "3", Type: Number, Line: 1},
+		{Lexeme: "==", Type: EqualEqual, Line: 1},
+		{Lexeme: "6", Type: Number, Line: 1},
+		{Lexeme: ";", Type: Semicolon, Line: 1},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -332,7 +332,7 @@ func TestPeek(t *testing.T) {
 	// pretending we are in the big switch statement in scanner.go (i.e: switch scan.scanToken())
 	scan.advance()
 	// now this is where we would be invoking a peek()
-	if !(scan.peek(byte(expected))) {
+	if !(scan.peek(expected)) {
 		t.Error("Did not peek expected value", expected)
 	}
 }
@@ -346,11 +346,11 @@ func TestMatchStr(t *testing.T) {
 	}
 
 	expectedTokens := []Token{
-		{"{", LeftBrace, 1},
-		{"true", True, 1},
-		{"print", Print, 1},
-		{"var", Var, 1},
-		{"}", RightBrace, 1},
+		{Lexeme: "{", Type: LeftBrace, Line: 1},
+		{Lexeme: "true", Type: True, Line: 1},
+		{Lexeme: "print", Type: Print, Line: 1},
+		{Lexeme: "var", Type: Var, Line: 1},
+		{Lexeme: "}", Type: RightBrace, Line: 1},
 	}
 
 	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
@@ -360,6 +360,428 @@ func TestMatchStr(t *testing.T) {
 	t.Log(tokens)
 }
 
+func TestScanLogical(t *testing.T) {
+	input := "a and b && order or c || android"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "a", Type: Identifier, Line: 1},
+		{Lexeme: "and", Type: And, Line: 1},
+		{Lexeme: "b", Type: Identifier, Line: 1},
+		{Lexeme: "&&", Type: And, Line: 1},
+		{Lexeme: "order", Type: Identifier, Line: 1},
+		{Lexeme: "or", Type: Or, Line: 1},
+		{Lexeme: "c", Type: Identifier, Line: 1},
+		{Lexeme: "||", Type: Or, Line: 1},
+		{Lexeme: "android", Type: Identifier, Line: 1},
+	}
+
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+}
+
+func TestScanKeywords(t *testing.T) {
+	for keyword, tokenType := range keywords {
+		scan := Scanner{}
+		tokens, err := scan.Scan(keyword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != 1 || tokens[0].Type != tokenType {
+			t.Errorf("expected '%s' to be scanned as a single %s, got %v", keyword, MasterTokenMap[tokenType], tokens)
+		}
+	}
+}
+
+// Identifiers that start with, end with or contain a keyword used to be split into a keyword and an identifier
+func TestScanKeywordPrefixedIdentifiers(t *testing.T) {
+	identifiers := []string{
+		"iffy", "format", "variance", "printer", "classes", "nilValue", "forest", "funcs", "falsey",
+		"elsewhere", "truthy", "returned", "whiles", "orange", "android", "breakfast", "continued", "superb",
+		"ifs", "fortune", "class_", "nil9", "var_", "_", "_private", "__init__", "x9", "v2_0", "PRINT", "If",
+		"thisIsFine", "résumé", "π",
+	}
+	for _, identifier := range identifiers {
+		scan := Scanner{}
+		tokens, err := scan.Scan(identifier)
+		if err != nil {
+			t.Errorf("scanning '%s': %s", identifier, err)
+			continue
+		}
+		if len(tokens) != 1 || tokens[0].Type != Identifier || tokens[0].Lexeme != identifier {
+			t.Errorf("expected '%s' to be scanned as a single identifier, got %v", identifier, tokens)
+		}
+	}
+}
+
+func TestScanIdentifierStatements(t *testing.T) {
+	input := "var variance = nilValue or iffy;\nprint printer_9.format(classes);"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "var", Type: Var, Line: 1},
+		{Lexeme: "variance", Type: Identifier, Line: 1},
+		{Lexeme: "=", Type: Equal, Line: 1},
+		{Lexeme: "nilValue", Type: Identifier, Line: 1},
+		{Lexeme: "or", Type: Or, Line: 1},
+		{Lexeme: "iffy", Type: Identifier, Line: 1},
+		{Lexeme: ";", Type: Semicolon, Line: 1},
+		{Lexeme: "print", Type: Print, Line: 2},
+		{Lexeme: "printer_9", Type: Identifier, Line: 2},
+		{Lexeme: ".", Type: Dot, Line: 2},
+		{Lexeme: "format", Type: Identifier, Line: 2},
+		{Lexeme: "(", Type: LeftParen, Line: 2},
+		{Lexeme: "classes", Type: Identifier, Line: 2},
+		{Lexeme: ")", Type: RightParen, Line: 2},
+		{Lexeme: ";", Type: Semicolon, Line: 2},
+	}
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+}
+
+func TestScanUnknownToken(t *testing.T) {
+	for _, input := range []string{"a @ b", "9 & 3", "x | y", "#"} {
+		scan := Scanner{}
+		_, err := scan.Scan(input)
+		if _, ok := err.(UnknownToken); !ok {
+			t.Errorf("expected an UnknownToken error scanning '%s', got %v", input, err)
+		}
+	}
+}
+
+func TestScanNumbers(t *testing.T) {
+	numbers := map[string]Value{
+		"0":           intValue(0),
+		"42":          intValue(42),
+		"007":         intValue(7),
+		"1_000_000":   intValue(1000000),
+		"0xFF":        intValue(255),
+		"0Xff_ff":     intValue(65535),
+		"0b1010":      intValue(10),
+		"0B1111_0000": intValue(240),
+		"3.14":        numberValue(3.14),
+		"1_000.5":     numberValue(1000.5),
+		"1e-9":        numberValue(1e-9),
+		"6.022E23":    numberValue(6.022e23),
+		"2.5e+2":      numberValue(250.0),
+		"1e1_0":       numberValue(1e10),
+	}
+	for lexeme, expected := range numbers {
+		scan := Scanner{}
+		tokens, err := scan.Scan(lexeme)
+		if err != nil {
+			t.Errorf("scanning '%s': %s", lexeme, err)
+			continue
+		}
+		if len(tokens) != 1 || tokens[0].Type != Number {
+			t.Errorf("expected '%s' to be a single Number, got %v", lexeme, tokens)
+			continue
+		}
+		if tokens[0].value != expected {
+			t.Errorf("expected '%s' to be %v (%s), got %v (%s)", lexeme, expected, expected.kind, tokens[0].value, tokens[0].value.kind)
+		}
+	}
+
+	// A '.' that isn't followed by a digit isn't part of the number
+	scan := Scanner{}
+	tokens, err := scan.Scan("grid[0].length")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 6 || tokens[3].Lexeme != "]" || tokens[4].Type != Dot {
+		t.Errorf("expected the '.' after '0]' to be a Dot, got %v", tokens)
+	}
+}
+
+func TestScanInvalidNumber(t *testing.T) {
+	inputs := []string{"1.2.3", "12px", "0b102", "0x", "0xG", "1__000", "1_", "1_.5", "0x_FF", "1e", "1e+", "99999999999999999999", "1e400"}
+	for _, input := range inputs {
+		scan := Scanner{}
+		_, err := scan.Scan(input)
+		if _, ok := err.(InvalidNumber); !ok {
+			t.Errorf("expected an InvalidNumber error scanning '%s', got %v", input, err)
+		}
+	}
+
+	scan := Scanner{}
+	_, err := scan.Scan("var x = 1;\nvar y = 1.2.3;")
+	invalid, ok := err.(InvalidNumber)
+	if !ok {
+		t.Fatalf("expected an InvalidNumber error, got %v", err)
+	}
+	if invalid.token.Lexeme != "1.2.3" || invalid.token.Line != 2 || invalid.token.Column != 9 {
+		t.Errorf("expected the error at '1.2.3' on 2:9, got '%s' on %d:%d", invalid.token.Lexeme, invalid.token.Line, invalid.token.Column)
+	}
+}
+
+func TestScanBlockComment(t *testing.T) {
+	input := "a /* one\n/* nested\n */ still a comment\n*/ b /**/ c // end"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "a", Type: Identifier, Line: 1},
+		{Lexeme: "b", Type: Identifier, Line: 4},
+		{Lexeme: "c", Type: Identifier, Line: 4},
+	}
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+
+	for _, input := range []string{"/* unclosed", "/* /* nested */ unclosed", "a /"} {
+		_, err := scan.Scan(input)
+		if input == "a /" {
+			if err != nil {
+				t.Errorf("expected a trailing '/' to be a Slash, got %v", err)
+			}
+			continue
+		}
+		if _, ok := err.(UnclosedComment); !ok {
+			t.Errorf("expected an UnclosedComment error scanning '%s', got %v", input, err)
+		}
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	input := "" +
+		"// Area of a rectangle.\n" +
+		"/* Takes the width\n   and height. */\n" +
+		"func area(x, y) { // trailing\n" +
+		"    return x * y; /* also trailing */ /* again */\n" +
+		"}\n" +
+		"// dangling"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	function := tokens[0]
+	if len(function.Leading) != 2 || function.Leading[0].Text != "// Area of a rectangle." || !function.Leading[1].Block {
+		t.Errorf("expected two leading comments on 'func', got %v", function.Leading)
+	}
+	if doc := function.Doc(); doc != "Area of a rectangle.\nTakes the width\nand height." {
+		t.Errorf("doc did not match, got %q", doc)
+	}
+
+	brace := tokens[7]
+	if brace.Lexeme != "{" || len(brace.Trailing) != 1 || brace.Trailing[0].Text != "// trailing" || brace.Trailing[0].Line != 4 {
+		t.Errorf("expected '// trailing' to trail '{', got %v", brace.Trailing)
+	}
+	semicolon := tokens[12]
+	if semicolon.Lexeme != ";" || len(semicolon.Trailing) != 2 {
+		t.Errorf("expected two comments to trail ';', got %v", semicolon.Trailing)
+	}
+	if closing := tokens[13]; len(closing.Leading) != 0 {
+		t.Errorf("expected no comments leading '}', got %v", closing.Leading)
+	}
+	if eof := scan.eof(); len(eof.Leading) != 1 || eof.Leading[0].Text != "// dangling" {
+		t.Errorf("expected the last comment to lead EOF, got %v", eof.Leading)
+	}
+}
+
+func TestScanPositions(t *testing.T) {
+	input := "var x = \"hi\";\n\tprint x >= 10;"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "var", Type: Var, Line: 1, Column: 1, Start: 0, End: 3},
+		{Lexeme: "x", Type: Identifier, Line: 1, Column: 5, Start: 4, End: 5},
+		{Lexeme: "=", Type: Equal, Line: 1, Column: 7, Start: 6, End: 7},
+		{Lexeme: "hi", Type: String, Line: 1, Column: 9, Start: 8, End: 12},
+		{Lexeme: ";", Type: Semicolon, Line: 1, Column: 13, Start: 12, End: 13},
+		{Lexeme: "print", Type: Print, Line: 2, Column: 2, Start: 15, End: 20},
+		{Lexeme: "x", Type: Identifier, Line: 2, Column: 8, Start: 21, End: 22},
+		{Lexeme: ">=", Type: GreaterEqual, Line: 2, Column: 10, Start: 23, End: 25},
+		{Lexeme: "10", Type: Number, Line: 2, Column: 13, Start: 26, End: 28},
+		{Lexeme: ";", Type: Semicolon, Line: 2, Column: 15, Start: 28, End: 29},
+	}
+
+	if len(tokens) != len(expectedTokens) {
+		t.Fatalf("tokens are not the same length as expected: %d - %d", len(tokens), len(expectedTokens))
+	}
+	for i, expect := range expectedTokens {
+		got := tokens[i]
+		if got.Column != expect.Column || got.Start != expect.Start || got.End != expect.End {
+			t.Errorf("position of %s: want %d:%d-%d, got %d:%d-%d", got, expect.Column, expect.Start, expect.End, got.Column, got.Start, got.End)
+		}
+	}
+}
+
+func TestScanStringEscapes(t *testing.T) {
+	input := `"a\"b" "tab\there\n" "back\\slash" "\u{1F600}\u{e9}" "null\0"`
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a\"b", "tab\there\n", "back\\slash", "\U0001F600\u00e9", "null\x00"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, lexeme := range expected {
+		if tokens[i].Lexeme != lexeme {
+			t.Errorf("expected lexeme %q, got %q", lexeme, tokens[i].Lexeme)
+		}
+	}
+}
+
+func TestScanInvalidEscape(t *testing.T) {
+	inputs := []string{`"\q"`, `"\u{}"`, `"\u{110000}"`, `"\u{41"`, `"\`}
+	for _, input := range inputs {
+		scan := Scanner{}
+		if _, err := scan.Scan(input); err == nil {
+			t.Errorf("expected an error scanning %s", input)
+		}
+	}
+
+	scan := Scanner{}
+	_, err := scan.Scan("var s =\n  \"ok \\x\";")
+	invalid, ok := err.(InvalidEscape)
+	if !ok {
+		t.Fatalf("expected an InvalidEscape error, got %v", err)
+	}
+	if invalid.token.Lexeme != "\\x" || invalid.token.Line != 2 || invalid.token.Column != 7 {
+		t.Errorf("expected the error at '\\x' on 2:7, got '%s' on %d:%d", invalid.token.Lexeme, invalid.token.Line, invalid.token.Column)
+	}
+}
+
+func TestScanRawString(t *testing.T) {
+	input := "var s = `no \\n escapes\n\"here\"`;\nprint s;"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "var", Type: Var, Line: 1},
+		{Lexeme: "s", Type: Identifier, Line: 1},
+		{Lexeme: "=", Type: Equal, Line: 1},
+		{Lexeme: "no \\n escapes\n\"here\"", Type: String, Line: 1},
+		{Lexeme: ";", Type: Semicolon, Line: 2},
+		{Lexeme: "print", Type: Print, Line: 3},
+		{Lexeme: "s", Type: Identifier, Line: 3},
+		{Lexeme: ";", Type: Semicolon, Line: 3},
+	}
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+	if semicolon := tokens[4]; semicolon.Column != 8 {
+		t.Errorf("expected the ';' after the raw string on column 8, got %d", semicolon.Column)
+	}
+
+	if _, err := scan.Scan("`unclosed\n"); err == nil {
+		t.Error("expected an unclosed raw string to be an error")
+	}
+}
+
+func TestScanInterpolation(t *testing.T) {
+	input := "\"a ${x + {\"k\": 1}[\"k\"]} b ${\"c${y}\"}\" }"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "a ", Type: StringPart, Line: 1},
+		{Lexeme: "x", Type: Identifier, Line: 1},
+		{Lexeme: "+", Type: Plus, Line: 1},
+		{Lexeme: "{", Type: LeftBrace, Line: 1},
+		{Lexeme: "k", Type: String, Line: 1},
+		{Lexeme: ":", Type: Colon, Line: 1},
+		{Lexeme: "1", Type: Number, Line: 1},
+		{Lexeme: "}", Type: RightBrace, Line: 1},
+		{Lexeme: "[", Type: LeftBracket, Line: 1},
+		{Lexeme: "k", Type: String, Line: 1},
+		{Lexeme: "]", Type: RightBracket, Line: 1},
+		{Lexeme: " b ", Type: StringPart, Line: 1},
+		{Lexeme: "c", Type: StringPart, Line: 1},
+		{Lexeme: "y", Type: Identifier, Line: 1},
+		{Lexeme: "", Type: String, Line: 1},
+		{Lexeme: "", Type: String, Line: 1},
+		{Lexeme: "}", Type: RightBrace, Line: 1},
+	}
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+
+	for _, input := range []string{"\"a ${x\"", "\"a ${x", "\"${ {}\""} {
+		if _, err := scan.Scan(input); err == nil {
+			t.Errorf("expected an error scanning %s", input)
+		}
+	}
+}
+
+func TestScanUnicode(t *testing.T) {
+	input := "var café = \"naïve ☕\"; π + 1"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTokens := []Token{
+		{Lexeme: "var", Type: Var, Line: 1, Column: 1},
+		{Lexeme: "café", Type: Identifier, Line: 1, Column: 5},
+		{Lexeme: "=", Type: Equal, Line: 1, Column: 10},
+		{Lexeme: "naïve ☕", Type: String, Line: 1, Column: 12},
+		{Lexeme: ";", Type: Semicolon, Line: 1, Column: 21},
+		{Lexeme: "π", Type: Identifier, Line: 1, Column: 23},
+		{Lexeme: "+", Type: Plus, Line: 1, Column: 25},
+		{Lexeme: "1", Type: Number, Line: 1, Column: 27},
+	}
+	if matched, got, expect := tokenMatch(t, tokens, expectedTokens); !matched {
+		gotExpectError(t, got, expect)
+	}
+	for i, expect := range expectedTokens {
+		if tokens[i].Column != expect.Column {
+			t.Errorf("expected '%s' on column %d, got %d", expect.Lexeme, expect.Column, tokens[i].Column)
+		}
+	}
+}
+
+func TestTokenExcerpt(t *testing.T) {
+	input := "var x = 1;\n\tprint x + nil;"
+	scan := Scanner{}
+	tokens, err := scan.Scan(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plus := tokens[7]
+	expected := " --> 2:10\n" +
+		"2 | \tprint x + nil;\n" +
+		"  | \t        ^"
+	if got := plus.excerpt(); got != expected {
+		t.Errorf("excerpt did not match:\n%s\nwant:\n%s", got, expected)
+	}
+
+	span := tokens[6].through(tokens[8])
+	if span.Lexeme != "x + nil" {
+		t.Errorf("span lexeme did not match: got '%s'", span.Lexeme)
+	}
+}
+
 func BenchmarkScanner(b *testing.B) {
 	input := "" +
 		"for(var i=0; i < 5; i++) {\n" +
@@ -382,7 +804,7 @@ func tokenMatch(t *testing.T, tokens []Token, expected []Token) (bool, *Token, *
 
 	for i, token := range expected {
 		//t.Logf("matching: | expected: %s | got: %s |", token, tokens[i])
-		if token == tokens[i] {
+		if token.Lexeme == tokens[i].Lexeme && token.Type == tokens[i].Type && token.Line == tokens[i].Line {
 			//t.Log(" - match\n")
 			continue
 		}
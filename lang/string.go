@@ -6,7 +6,11 @@ import (
 )
 
 func (stmt FunctionDeclarationStatement) String() string {
-	return fmt.Sprintf("func: %s | args: %v | block: %v |\n", stmt.Identifier.Lexeme, *stmt.args, stmt.block)
+	var args []Token
+	if stmt.args != nil {
+		args = *stmt.args
+	}
+	return fmt.Sprintf("func: %s | args: %v | block: %v |\n", stmt.Identifier.Lexeme, args, stmt.block)
 }
 
 func (stmt ReturnStatement) String() string {
@@ -33,26 +37,10 @@ func (block Block) String() string {
 	return b.String()
 }
 
-func (vars varMap) String() string {
-	sb := strings.Builder{}
-	for lexeme, value := range vars {
-		if value != nil {
-			sb.WriteString(fmt.Sprintf("%s=%v\n", lexeme, *value))
-			continue
-		}
-		sb.WriteString(fmt.Sprintf("%s=%v\n", lexeme, nil))
-	}
-	return sb.String()
-}
-
-func (funcs funcMap) String() string {
+func (vars *varMap) String() string {
 	sb := strings.Builder{}
-	for name, invocation := range funcs {
-		if invocation.stmt.args != nil {
-			sb.WriteString(fmt.Sprintf("%s(%v)", name, *invocation.stmt.args))
-			continue
-		}
-		sb.WriteString(fmt.Sprintf("%s()", name))
+	for slot, name := range vars.declared() {
+		sb.WriteString(fmt.Sprintf("%s=%v\n", name, vars.values[slot]))
 	}
 	return sb.String()
 }
@@ -1,6 +1,10 @@
 package lang
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
 
 const (
 	LeftParen = iota
@@ -12,6 +16,7 @@ const (
 	Comma
 	Dot
 	Semicolon
+	Colon
 	Slash
 	Star
 	Mod
@@ -28,15 +33,21 @@ const (
 	GreaterEqual
 	Less
 	LessEqual
+	Arrow
 
 	Identifier
 	String
+	StringPart // The part of a string before an interpolated '${expression}'
 	Number
 
 	And
+	Break
+	Catch
 	Class
+	Continue
 	Else
 	False
+	Finally
 	Function
 	For
 	If
@@ -46,7 +57,9 @@ const (
 	Return
 	Super
 	This
+	Throw
 	True
+	Try
 	Var
 	While
 
@@ -54,31 +67,189 @@ const (
 )
 
 // Token is a parsed sequence of character terminal(s)
-// TODO: maybe store column position as well?
 type Token struct {
 	Lexeme string
 	Type   int
 	Line   uint
+	Column uint // 1-based column of the first character of the token on Line
+	Start  uint // Byte offset of the first character of the token in the source
+	End    uint // Byte offset one past the last character of the token in the source
+	src    *Source
+	value  Value // The parsed value of a Number, so it only has to be parsed once
+
+	Leading  []Trivia // Comments on the lines before the token
+	Trailing []Trivia // Comments after the token on the same line
+}
+
+// Trivia is a comment, which the scanner attaches to the Token it's next to so tooling can recover it
+type Trivia struct {
+	Text  string // The whole comment including its '//' or '/* */'
+	Line  uint
+	Start uint
+	End   uint
+	Block bool
+}
+
+// Doc is the comment directly above the Token, without the comment markers, i.e the doc comment of a declaration.
+// Comments that are separated from the Token by a blank line aren't part of it.
+func (t Token) Doc() string {
+	lines := make([]string, 0)
+	line := t.Line
+	for i := len(t.Leading) - 1; i >= 0; i-- {
+		trivia := t.Leading[i]
+		text := trivia.Text
+		if trivia.Block {
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		} else {
+			text = strings.TrimPrefix(text, "//")
+		}
+		commentLines := strings.Split(text, "\n")
+		if trivia.Line+uint(len(commentLines)) != line {
+			break
+		}
+		line = trivia.Line
+
+		for j := len(commentLines) - 1; j >= 0; j-- {
+			text := strings.TrimSpace(commentLines[j])
+			if trivia.Block {
+				text = strings.TrimSpace(strings.TrimPrefix(text, "*"))
+			}
+			lines = append([]string{text}, lines...)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Source is the named input text a Token was scanned from.
+// Name is empty when the input didn't come from a file (i.e the repl).
+type Source struct {
+	Name string
+	Text string
 }
 
 func (t Token) is(ta int) bool {
 	return t.Type == ta
 }
 
+// Position is the human readable 'file:line:column' location of the Token.
+func (t Token) Position() string {
+	if t.src != nil && t.src.Name != "" {
+		return fmt.Sprintf("%s:%d:%d", t.src.Name, t.Line, t.Column)
+	}
+	return fmt.Sprintf("%d:%d", t.Line, t.Column)
+}
+
+// through returns a Token spanning from the start of t to the end of end.
+// Both tokens must have been scanned from the same source, otherwise t is returned untouched.
+func (t Token) through(end Token) Token {
+	if t.src == nil || t.src != end.src || end.End < t.Start {
+		return t
+	}
+	t.End = end.End
+	t.Lexeme = t.src.Text[t.Start:t.End]
+	return t
+}
+
+// last returns a Token of just the last n bytes of t, i.e the '${' at the end of a StringPart.
+// A Token that wasn't scanned from a source is returned untouched.
+func (t Token) last(n uint) Token {
+	if t.src == nil || t.End-t.Start < n {
+		return t
+	}
+	text, start := t.src.Text, t.End-n
+	lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+	t.Line += uint(strings.Count(text[t.Start:start], "\n"))
+	t.Column = uint(utf8.RuneCountInString(text[lineStart:start])) + 1
+	t.Start = start
+	t.Lexeme = text[start:t.End]
+	return t
+}
+
+// excerpt renders the source line the Token was scanned from with a caret under the Token's span.
+// Tokens that weren't scanned from a source (i.e builtins) have no excerpt.
+func (t Token) excerpt() string {
+	if t.src == nil || t.Start > uint(len(t.src.Text)) {
+		return ""
+	}
+	text := t.src.Text
+	lineStart := strings.LastIndexByte(text[:t.Start], '\n') + 1
+	lineEnd := strings.IndexByte(text[t.Start:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text)
+	} else {
+		lineEnd += int(t.Start)
+	}
+	end := int(t.End)
+	if end > lineEnd {
+		end = lineEnd
+	}
+
+	gutter := fmt.Sprintf("%d", t.Line)
+	pad := strings.Repeat(" ", len(gutter))
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s--> %s\n", pad, t.Position()))
+	sb.WriteString(fmt.Sprintf("%s | %s\n", gutter, text[lineStart:lineEnd]))
+	sb.WriteString(fmt.Sprintf("%s | ", pad))
+	// Keep tabs so the caret lines up with the source line however the terminal renders them
+	for _, r := range text[lineStart:t.Start] {
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	width := 1
+	if end > int(t.Start) {
+		width = utf8.RuneCountInString(text[t.Start:end])
+	}
+	sb.WriteString(strings.Repeat("^", width))
+
+	return sb.String()
+}
+
 func (t Token) String() string {
 	return fmt.Sprintf("Token<'%s'|%d|%s>", t.Lexeme, t.Line, t.TypeString())
 }
 
+// keywords are the reserved identifiers and the Token they're scanned as
+var keywords = map[string]int{
+	"and":      And,
+	"break":    Break,
+	"catch":    Catch,
+	"class":    Class,
+	"continue": Continue,
+	"else":     Else,
+	"false":    False,
+	"finally":  Finally,
+	"for":      For,
+	"func":     Function,
+	"if":       If,
+	"nil":      Nil,
+	"or":       Or,
+	"print":    Print,
+	"return":   Return,
+	"super":    Super,
+	"throw":    Throw,
+	"true":     True,
+	"try":      Try,
+	"var":      Var,
+	"while":    While,
+}
+
 var MasterTokenMap = map[int]string{
 	LeftParen:    "LeftParen",
 	RightParen:   "RightParen",
 	LeftBrace:    "LeftBrace",
 	RightBrace:   "RightBrace",
+	LeftBracket:  "LeftBracket",
+	RightBracket: "RightBracket",
 	Comma:        "Comma",
 	Dot:          "Dot",
 	Semicolon:    "Semicolon",
+	Colon:        "Colon",
 	Slash:        "Slash",
 	Star:         "Star",
+	Mod:          "Mod",
 	Plus:         "Plus",
 	PlusPlus:     "PlusPlus",
 	Minus:        "Minus",
@@ -91,13 +262,19 @@ var MasterTokenMap = map[int]string{
 	GreaterEqual: "GreaterEqual",
 	Less:         "Less",
 	LessEqual:    "LessEqual",
+	Arrow:        "Arrow",
 	Identifier:   "Identifier",
 	String:       "String",
+	StringPart:   "StringPart",
 	Number:       "Number",
 	And:          "And",
+	Break:        "Break",
+	Catch:        "Catch",
 	Class:        "Class",
+	Continue:     "Continue",
 	Else:         "Else",
 	False:        "False",
+	Finally:      "Finally",
 	Function:     "Function",
 	For:          "For",
 	If:           "If",
@@ -107,7 +284,9 @@ var MasterTokenMap = map[int]string{
 	Return:       "Return",
 	Super:        "Super",
 	This:         "This",
+	Throw:        "Throw",
 	True:         "True",
+	Try:          "Try",
 	Var:          "Var",
 	While:        "While",
 	EOF:          "EOF",
@@ -124,5 +303,5 @@ func (t Token) TypeString() string {
 
 // A go fmtd Token array output, useful for making tests
 func (t Token) FmtString() string {
-	return fmt.Sprintf("Token{\"%s\", %s, %d}", t.Lexeme, MasterTokenMap[t.Type], t.Line)
+	return fmt.Sprintf("{Lexeme: \"%s\", Type: %s, Line: %d}", t.Lexeme, MasterTokenMap[t.Type], t.Line)
 }
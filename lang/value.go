@@ -0,0 +1,460 @@
+package lang
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValueKind is what type of value a Value is
+type ValueKind uint8
+
+const (
+	NilKind ValueKind = iota
+	BoolKind
+	IntKind
+	NumberKind // A float i.e '3.14' or '1e-9'
+	StringKind
+	ArrayKind
+	MapKind
+	FunctionKind
+	ClassKind
+	InstanceKind
+)
+
+var kindNames = [...]string{"nil", "bool", "int", "number", "string", "array", "map", "function", "class", "instance"}
+
+func (kind ValueKind) String() string {
+	return kindNames[kind]
+}
+
+// Value is the base atom for all jlang types. It's tagged with its ValueKind, bools, ints and numbers are
+// kept in bits so they never need allocating, and everything else is kept in ref. The zero Value is nil.
+type Value struct {
+	kind ValueKind
+	bits uint64
+	ref  interface{}
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return Value{kind: BoolKind, bits: 1}
+	}
+	return Value{kind: BoolKind}
+}
+
+func intValue(n int) Value {
+	return Value{kind: IntKind, bits: uint64(n)}
+}
+
+func numberValue(f float64) Value {
+	return Value{kind: NumberKind, bits: math.Float64bits(f)}
+}
+
+func stringValue(s string) Value {
+	return Value{kind: StringKind, ref: s}
+}
+
+// arrayValue is an array of elems. Arrays are shared by reference wherever they get stored or passed.
+func arrayValue(elems []Value) Value {
+	return Value{kind: ArrayKind, ref: elems}
+}
+
+func mapValue(m *JlangMap) Value {
+	return Value{kind: MapKind, ref: m}
+}
+
+func functionValue(fun JlangFunction) Value {
+	return Value{kind: FunctionKind, ref: fun}
+}
+
+func classValue(class *JlangClass) Value {
+	return Value{kind: ClassKind, ref: class}
+}
+
+func instanceValue(instance JlangClassInstance) Value {
+	return Value{kind: InstanceKind, ref: instance}
+}
+
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+func (v Value) isNil() bool {
+	return v.kind == NilKind
+}
+
+func (v Value) asBool() bool {
+	return v.bits == 1
+}
+
+func (v Value) asInt() int {
+	return int(v.bits)
+}
+
+func (v Value) asNumber() float64 {
+	return math.Float64frombits(v.bits)
+}
+
+func (v Value) asString() string {
+	return v.ref.(string)
+}
+
+func (v Value) asArray() []Value {
+	return v.ref.([]Value)
+}
+
+func (v Value) asMap() *JlangMap {
+	return v.ref.(*JlangMap)
+}
+
+func (v Value) asFunction() JlangFunction {
+	return v.ref.(JlangFunction)
+}
+
+func (v Value) asClass() *JlangClass {
+	return v.ref.(*JlangClass)
+}
+
+func (v Value) asInstance() JlangClassInstance {
+	return v.ref.(JlangClassInstance)
+}
+
+// callable is the Callable a function or class is
+func (v Value) callable() (Callable, bool) {
+	switch v.kind {
+	case FunctionKind, ClassKind:
+		// Asserting the ref as a Callable doesn't copy the function the way converting asFunction() would
+		return v.ref.(Callable), true
+	}
+	return nil, false
+}
+
+// float is an int or number as a float64, and whether it is one
+func (v Value) float() (float64, bool) {
+	switch v.kind {
+	case IntKind:
+		return float64(v.asInt()), true
+	case NumberKind:
+		return v.asNumber(), true
+	}
+	return 0, false
+}
+
+// truthy is only true for true itself, everything else is falsy
+func (v Value) truthy() bool {
+	return v.kind == BoolKind && v.asBool()
+}
+
+// String is how a Value is shown when it's printed or concatenated with a string
+func (v Value) String() string {
+	return v.format(nil)
+}
+
+// format is String for a value inside the containers in seen, which are already being shown. A container
+// that's inside itself is shown as '[...]' or '{...}' when it comes up again, instead of forever.
+func (v Value) format(seen map[interface{}]bool) string {
+	switch v.kind {
+	case NilKind:
+		return "<nil>"
+	case BoolKind:
+		return strconv.FormatBool(v.asBool())
+	case IntKind:
+		return strconv.Itoa(v.asInt())
+	case NumberKind:
+		return strconv.FormatFloat(v.asNumber(), 'g', -1, 64)
+	case StringKind:
+		return v.asString()
+	case ArrayKind:
+		arr := v.asArray()
+		if len(arr) == 0 {
+			return "[]"
+		}
+		// An array is the same array as another when they share their elements
+		if seen[&arr[0]] {
+			return "[...]"
+		}
+		if seen == nil {
+			seen = make(map[interface{}]bool)
+		}
+		seen[&arr[0]] = true
+		defer delete(seen, &arr[0])
+
+		elems := make([]string, len(arr))
+		for i, elem := range arr {
+			elems[i] = elem.format(seen)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case MapKind:
+		return v.asMap().format(seen)
+	case FunctionKind:
+		return v.asFunction().String()
+	case ClassKind:
+		return v.asClass().String()
+	case InstanceKind:
+		return v.asInstance().format(seen)
+	}
+	return ""
+}
+
+// plus adds two ints or numbers, joins anything to a string, or is whether two bools are both true
+func (v Value) plus(other Value) (Value, bool) {
+	switch {
+	case v.kind == IntKind && other.kind == IntKind:
+		return intValue(v.asInt() + other.asInt()), true
+	case v.kind == StringKind || (v.kind == IntKind && other.kind == StringKind):
+		return stringValue(v.String() + other.String()), true
+	case v.kind == BoolKind && other.kind == BoolKind:
+		return boolValue(v.asBool() && other.asBool()), true
+	}
+	return v.arithmetic(other, func(l, r float64) float64 { return l + r })
+}
+
+func (v Value) minus(other Value) (Value, bool) {
+	if v.kind == IntKind && other.kind == IntKind {
+		return intValue(v.asInt() - other.asInt()), true
+	}
+	return v.arithmetic(other, func(l, r float64) float64 { return l - r })
+}
+
+func (v Value) times(other Value) (Value, bool) {
+	if v.kind == IntKind && other.kind == IntKind {
+		return intValue(v.asInt() * other.asInt()), true
+	}
+	return v.arithmetic(other, func(l, r float64) float64 { return l * r })
+}
+
+// divide is integer division for two ints, checking for zero is up to the caller
+func (v Value) divide(other Value) (Value, bool) {
+	if v.kind == IntKind && other.kind == IntKind {
+		return intValue(v.asInt() / other.asInt()), true
+	}
+	return v.arithmetic(other, func(l, r float64) float64 { return l / r })
+}
+
+// modulo is only for ints, checking for zero is up to the caller
+func (v Value) modulo(other Value) (Value, bool) {
+	if v.kind == IntKind && other.kind == IntKind {
+		return intValue(v.asInt() % other.asInt()), true
+	}
+	return Value{}, false
+}
+
+// arithmetic is op on two numbers, or an int and a number, which is a number
+func (v Value) arithmetic(other Value, op func(l, r float64) float64) (Value, bool) {
+	if v.kind != NumberKind && other.kind != NumberKind {
+		return Value{}, false
+	}
+	l, ok := v.float()
+	if !ok {
+		return Value{}, false
+	}
+	r, ok := other.float()
+	if !ok {
+		return Value{}, false
+	}
+	return numberValue(op(l, r)), true
+}
+
+// less orders ints and numbers by value, strings lexicographically, and arrays by their first elements
+// that aren't equal, then by length. It's only ok if both values can be ordered against each other.
+func (v Value) less(other Value) (bool, bool) {
+	return v.before(other, false, nil)
+}
+
+// before is less, or less or equal when orEqual, for values inside the pairs of arrays in comparing. A pair
+// that comes up again while it's being compared doesn't come before itself.
+func (v Value) before(other Value, orEqual bool, comparing map[[2]interface{}]bool) (bool, bool) {
+	switch {
+	case v.kind == IntKind && other.kind == IntKind:
+		if orEqual {
+			return v.asInt() <= other.asInt(), true
+		}
+		return v.asInt() < other.asInt(), true
+	case v.kind == StringKind && other.kind == StringKind:
+		if orEqual {
+			return v.asString() <= other.asString(), true
+		}
+		return v.asString() < other.asString(), true
+	case v.kind == ArrayKind && other.kind == ArrayKind:
+		l, r := v.asArray(), other.asArray()
+		if len(l) > 0 && len(r) > 0 {
+			pair := [2]interface{}{&l[0], &r[0]}
+			if comparing[pair] {
+				return false, true
+			}
+			if comparing == nil {
+				comparing = make(map[[2]interface{}]bool)
+			}
+			comparing[pair] = true
+			defer delete(comparing, pair)
+		}
+		for i := 0; i < len(l) && i < len(r); i++ {
+			if !l[i].equals(r[i]) {
+				return l[i].before(r[i], false, comparing)
+			}
+		}
+		if orEqual {
+			return len(l) <= len(r), true
+		}
+		return len(l) < len(r), true
+	}
+	l, lok := v.float()
+	r, rok := other.float()
+	if orEqual {
+		return lok && rok && l <= r, lok && rok
+	}
+	return lok && rok && l < r, lok && rok
+}
+
+// key is the Value a map stores v under. A number with a whole value is stored as that int, since they're equal.
+func (v Value) key() Value {
+	if whole, ok := v.whole(); ok {
+		return intValue(whole)
+	}
+	return v
+}
+
+// whole is the int a number is equal to, if it has a whole value that an int can hold
+func (v Value) whole() (int, bool) {
+	if v.kind != NumberKind {
+		return 0, false
+	}
+	f := v.asNumber()
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// equals is whether two values are equal, without calling any 'equals' methods on instances
+func (v Value) equals(other Value) bool {
+	eq, _ := v.equal(nil, Token{}, other)
+	return eq
+}
+
+// equal is whether two values are the same kind with the same value, or are an int and a number with the
+// same value. Arrays are equal when their elements are, and maps when they have equal values for the same keys. Functions and
+// classes are only equal to themselves, and so are instances unless their class has an 'equals' method,
+// which gets called with other when there's an intptr to call it with. site is where the comparison is.
+func (v Value) equal(intptr *Interpreter, site Token, other Value) (bool, error) {
+	return v.equalIn(intptr, site, other, nil)
+}
+
+// equalIn is equal for values inside the pairs of containers in comparing, which are already being compared.
+// A pair that comes up again while it's being compared is taken to be equal, so containers inside
+// themselves are compared by the rest of their elements instead of forever.
+func (v Value) equalIn(intptr *Interpreter, site Token, other Value, comparing map[[2]interface{}]bool) (bool, error) {
+	if v.kind != other.kind {
+		if v.kind == IntKind && other.kind == NumberKind {
+			whole, ok := other.whole()
+			return ok && whole == v.asInt(), nil
+		}
+		if v.kind == NumberKind && other.kind == IntKind {
+			whole, ok := v.whole()
+			return ok && whole == other.asInt(), nil
+		}
+		return false, nil
+	}
+	switch v.kind {
+	case NilKind:
+		return true, nil
+	case BoolKind, IntKind:
+		return v.bits == other.bits, nil
+	case NumberKind:
+		return v.asNumber() == other.asNumber(), nil
+	case StringKind:
+		return v.asString() == other.asString(), nil
+	case ArrayKind:
+		l, r := v.asArray(), other.asArray()
+		if len(l) != len(r) {
+			return false, nil
+		}
+		if len(l) == 0 || &l[0] == &r[0] {
+			return true, nil
+		}
+		pair := [2]interface{}{&l[0], &r[0]}
+		if comparing[pair] {
+			return true, nil
+		}
+		if comparing == nil {
+			comparing = make(map[[2]interface{}]bool)
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for i := range l {
+			if eq, err := l[i].equalIn(intptr, site, r[i], comparing); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case MapKind:
+		l, r := v.asMap(), other.asMap()
+		if l == r {
+			return true, nil
+		}
+		if len(l.keys) != len(r.keys) {
+			return false, nil
+		}
+		pair := [2]interface{}{l, r}
+		if comparing[pair] {
+			return true, nil
+		}
+		if comparing == nil {
+			comparing = make(map[[2]interface{}]bool)
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for _, key := range l.keys {
+			if !r.has(key) {
+				return false, nil
+			}
+			if eq, err := l.get(key).equalIn(intptr, site, r.get(key), comparing); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case FunctionKind:
+		return v.asFunction().same(other.asFunction()), nil
+	case ClassKind:
+		return v.asClass() == other.asClass(), nil
+	case InstanceKind:
+		instance := v.asInstance()
+		if method, found := instance.parent.findMethod("equals"); found && intptr != nil {
+			eq, err := intptr.callValue(functionValue(method.bind(instance)), site, []Value{other})
+			return eq.truthy(), err
+		}
+		return instance.same(other.asInstance()), nil
+	}
+	return false, nil
+}
+
+func (v Value) negate() (Value, bool) {
+	switch v.kind {
+	case IntKind:
+		return intValue(-v.asInt()), true
+	case NumberKind:
+		return numberValue(-v.asNumber()), true
+	}
+	return Value{}, false
+}
+
+func (v Value) not() (Value, bool) {
+	if v.kind == BoolKind {
+		return boolValue(!v.asBool()), true
+	}
+	return Value{}, false
+}
+
+// step is an int or number plus delta, for '++' and '--'
+func (v Value) step(delta int) (Value, bool) {
+	switch v.kind {
+	case IntKind:
+		return intValue(v.asInt() + delta), true
+	case NumberKind:
+		return numberValue(v.asNumber() + float64(delta)), true
+	}
+	return Value{}, false
+}
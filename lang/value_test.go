@@ -0,0 +1,174 @@
+package lang
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValueString(t *testing.T) {
+	values := map[string]Value{
+		"<nil>":              {},
+		"true":               boolValue(true),
+		"-3":                 intValue(-3),
+		"2.5":                numberValue(2.5),
+		"1e-09":              numberValue(1e-9),
+		"jlang":              stringValue("jlang"),
+		"[1, two, <nil>]":    arrayValue([]Value{intValue(1), stringValue("two"), {}}),
+		"[[false], 0.5]":     arrayValue([]Value{arrayValue([]Value{boolValue(false)}), numberValue(0.5)}),
+		"func(x)":            functionValue(JlangFunction{decl: FunctionDeclarationStatement{args: &[]Token{{Lexeme: "x"}}}}),
+		"class Empty":        classValue(&JlangClass{identifier: Token{Lexeme: "Empty"}}),
+		"[<nil>, true, 100]": arrayValue([]Value{{}, boolValue(true), intValue(100)}),
+	}
+	for expected, val := range values {
+		if val.String() != expected {
+			t.Errorf("expected %s value to be '%s', got '%s'", val.kind, expected, val.String())
+		}
+	}
+}
+
+func TestValueStringCycle(t *testing.T) {
+	inner := []Value{intValue(1), {}}
+	outer := []Value{arrayValue(inner)}
+	inner[1] = arrayValue(outer)
+	if str := arrayValue(outer).String(); str != "[[1, [...]]]" {
+		t.Errorf("expected the array inside itself to be shown as [...], got %s", str)
+	}
+
+	m := newJlangMap()
+	m.set(stringValue("self"), mapValue(m))
+	m.set(stringValue("list"), arrayValue([]Value{mapValue(m)}))
+	if str := mapValue(m).String(); str != "{self: {...}, list: [{...}]}" {
+		t.Errorf("expected the map inside itself to be shown as {...}, got %s", str)
+	}
+
+	// An array that's in another twice isn't inside itself
+	shared := arrayValue([]Value{intValue(2)})
+	if str := arrayValue([]Value{shared, shared}).String(); str != "[[2], [2]]" {
+		t.Errorf("expected the same array twice to be shown twice, got %s", str)
+	}
+}
+
+func TestValueArithmetic(t *testing.T) {
+	tests := []struct {
+		val      Value
+		expected Value
+	}{
+		{mustValue(intValue(1).plus(intValue(2))), intValue(3)},
+		{mustValue(intValue(1).plus(numberValue(0.5))), numberValue(1.5)},
+		{mustValue(stringValue("a").plus(intValue(1))), stringValue("a1")},
+		{mustValue(intValue(1).plus(stringValue("a"))), stringValue("1a")},
+		{mustValue(intValue(7).divide(intValue(2))), intValue(3)},
+		{mustValue(numberValue(7).divide(intValue(2))), numberValue(3.5)},
+		{mustValue(intValue(7).modulo(intValue(4))), intValue(3)},
+		{mustValue(intValue(3).step(-1)), intValue(2)},
+	}
+	for i, test := range tests {
+		if test.val.kind != test.expected.kind || !test.val.equals(test.expected) {
+			t.Errorf("%d: expected %s (%s), got %s (%s)", i, test.expected, test.expected.kind, test.val, test.val.kind)
+		}
+	}
+
+	if _, ok := boolValue(true).minus(intValue(1)); ok {
+		t.Errorf("expected bool - int to not be ok")
+	}
+	if _, ok := numberValue(1).modulo(intValue(1)); ok {
+		t.Errorf("expected number %% int to not be ok")
+	}
+}
+
+func TestValueEquals(t *testing.T) {
+	array := arrayValue([]Value{intValue(1), arrayValue([]Value{stringValue("two")})})
+	if !array.equals(arrayValue([]Value{intValue(1), arrayValue([]Value{stringValue("two")})})) {
+		t.Errorf("expected arrays with equal elements to be equal")
+	}
+	if array.equals(arrayValue([]Value{intValue(1), arrayValue([]Value{stringValue("three")})})) {
+		t.Errorf("expected arrays with different elements to not be equal")
+	}
+
+	l, r := newJlangMap(), newJlangMap()
+	l.set(stringValue("a"), intValue(1))
+	l.set(stringValue("b"), intValue(2))
+	r.set(stringValue("b"), intValue(2))
+	r.set(stringValue("a"), intValue(1))
+	if !mapValue(l).equals(mapValue(r)) {
+		t.Errorf("expected maps with the same entries in a different order to be equal")
+	}
+	r.set(stringValue("c"), Value{})
+	if mapValue(l).equals(mapValue(r)) {
+		t.Errorf("expected maps with different keys to not be equal")
+	}
+
+	if !intValue(1).equals(numberValue(1)) || !numberValue(-3).equals(intValue(-3)) {
+		t.Errorf("expected an int to equal a number with the same value")
+	}
+	if intValue(1).equals(numberValue(1.5)) || numberValue(math.Inf(1)).equals(intValue(math.MaxInt64)) {
+		t.Errorf("expected an int to not equal a number with a different value")
+	}
+	if !(Value{}).equals(Value{}) || (Value{}).equals(boolValue(false)) {
+		t.Errorf("expected nil to only equal nil")
+	}
+}
+
+func TestMapNumberKeys(t *testing.T) {
+	m := newJlangMap()
+	m.set(intValue(1), stringValue("one"))
+	m.set(numberValue(1), stringValue("uno"))
+	m.set(numberValue(1.5), stringValue("one and a half"))
+	if len(m.keys) != 2 || m.get(numberValue(1)).asString() != "uno" || !m.has(intValue(1)) {
+		t.Errorf("expected 1 and 1.0 to be the same key, got %s", m)
+	}
+	if !m.delete(numberValue(1)) || m.has(intValue(1)) || len(m.keys) != 1 {
+		t.Errorf("expected deleting 1.0 to delete 1, got %s", m)
+	}
+}
+
+func TestValueEqualsCycle(t *testing.T) {
+	l, r := []Value{intValue(1), {}}, []Value{intValue(1), {}}
+	l[1], r[1] = arrayValue(l), arrayValue(r)
+	if !arrayValue(l).equals(arrayValue(r)) {
+		t.Errorf("expected arrays that contain themselves the same way to be equal")
+	}
+	r[0] = intValue(2)
+	if arrayValue(l).equals(arrayValue(r)) {
+		t.Errorf("expected arrays that contain themselves with different elements to not be equal")
+	}
+
+	lm, rm := newJlangMap(), newJlangMap()
+	lm.set(stringValue("self"), mapValue(lm))
+	rm.set(stringValue("self"), mapValue(rm))
+	if !mapValue(lm).equals(mapValue(rm)) {
+		t.Errorf("expected maps that contain themselves the same way to be equal")
+	}
+}
+
+func TestValueLess(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		less, ok    bool
+	}{
+		{intValue(1), intValue(2), true, true},
+		{numberValue(2.5), intValue(2), false, true},
+		{stringValue("Zebra"), stringValue("apple"), true, true},
+		{stringValue("app"), stringValue("apple"), true, true},
+		{arrayValue([]Value{intValue(1), intValue(9)}), arrayValue([]Value{intValue(2)}), true, true},
+		{arrayValue([]Value{intValue(1)}), arrayValue([]Value{intValue(1)}), false, true},
+		{numberValue(math.NaN()), numberValue(1), false, true},
+		{arrayValue([]Value{intValue(1), intValue(2)}), arrayValue([]Value{numberValue(1), intValue(3)}), true, true},
+		{stringValue("1"), intValue(2), false, false},
+		{boolValue(false), boolValue(true), false, false},
+		{arrayValue([]Value{intValue(1)}), arrayValue([]Value{stringValue("1")}), false, false},
+	}
+	for _, test := range tests {
+		less, ok := test.left.less(test.right)
+		if less != test.less || ok != test.ok {
+			t.Errorf("expected %s < %s to be %t (ok %t), got %t (ok %t)", test.left, test.right, test.less, test.ok, less, ok)
+		}
+	}
+}
+
+func mustValue(val Value, ok bool) Value {
+	if !ok {
+		return Value{}
+	}
+	return val
+}
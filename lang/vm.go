@@ -0,0 +1,509 @@
+package lang
+
+import (
+	"fmt"
+	"strings"
+)
+
+// run runs compiled code in the current Environment until it returns. Variables live in the same blocks
+// and slots as when the tree walker runs, so compiled and walked code can call each other and share closures.
+// base is where the Environment starts in intptr.blocks when it's a call's, or -1 when it isn't.
+func (intptr *Interpreter) run(code *chunk, base int) (Value, error) {
+	frame := vmFrame{blocks: len(intptr.env.vars), base: base}
+	if n := len(intptr.stacks); n > 0 {
+		frame.stack, intptr.stacks = intptr.stacks[n-1], intptr.stacks[:n-1]
+	}
+	defer func() {
+		intptr.stacks = append(intptr.stacks, frame.stack[:0])
+	}()
+
+	for {
+		val, err := intptr.exec(code, &frame)
+		if err == nil {
+			return val, nil
+		}
+		// A function's blocks are dropped with its frame anyway, but the top level of a Program has to drop its own
+		if !frame.catch(intptr, err) {
+			frame.truncate(intptr, frame.blocks)
+			return Value{}, err
+		}
+	}
+}
+
+// vmFrame is the state of the code run is running, which is kept when an error is caught and it carries on
+type vmFrame struct {
+	ip       int
+	stack    vmStack
+	blocks   int // How many blocks there were when it started
+	base     int
+	handlers []vmHandler
+	pending  []pending // The errors that the finally blocks being run will rethrow
+}
+
+// pending is an error a finally will rethrow, and the calls it was raised in
+type pending struct {
+	err    error
+	failed []Frame
+}
+
+// vmHandler is where to jump to when the code in a try throws
+type vmHandler struct {
+	target int
+	stack  int // How big the stack and how many blocks there were at the try
+	blocks int
+	catch  bool // Whether it's a catch, which gets the error, or a finally, which rethrows it
+}
+
+// fail keeps the stack for when err is caught
+func (frame *vmFrame) fail(stack vmStack, err error) (Value, error) {
+	frame.stack = stack
+	return Value{}, err
+}
+
+// catch jumps to the innermost handler for err, if there is one and err can be caught
+func (frame *vmFrame) catch(intptr *Interpreter, err error) bool {
+	if len(frame.handlers) == 0 || !catchable(err) {
+		return false
+	}
+	handler := frame.handlers[len(frame.handlers)-1]
+	frame.handlers = frame.handlers[:len(frame.handlers)-1]
+	frame.truncate(intptr, handler.blocks)
+	frame.stack = frame.stack[:handler.stack]
+	if handler.catch {
+		frame.stack.push(intptr.caught(err))
+	} else {
+		frame.pending = append(frame.pending, pending{err, intptr.failed})
+		intptr.failed = nil
+	}
+	frame.ip = handler.target
+	return true
+}
+
+// push pushes a block onto the Environment, which is on top of intptr.blocks when it's a call's
+func (frame *vmFrame) push(intptr *Interpreter, block Block) {
+	if frame.base < 0 {
+		intptr.env.vars = append(intptr.env.vars, block)
+		return
+	}
+	intptr.blocks = append(intptr.blocks, block)
+	intptr.env.vars = intptr.blocks[frame.base:len(intptr.blocks):len(intptr.blocks)]
+}
+
+// truncate pops blocks off the Environment until there are only blocks of them
+func (frame *vmFrame) truncate(intptr *Interpreter, blocks int) {
+	if frame.base < 0 {
+		intptr.env.vars = intptr.env.vars[:blocks]
+		return
+	}
+	top := frame.base + blocks
+	for i := top; i < len(intptr.blocks); i++ {
+		intptr.blocks[i] = Block{}
+	}
+	intptr.blocks = intptr.blocks[:top]
+	intptr.env.vars = intptr.blocks[frame.base:top:top]
+}
+
+// exec runs code from where frame is up to when it returns or fails
+func (intptr *Interpreter) exec(code *chunk, frame *vmFrame) (Value, error) {
+	stack := frame.stack
+	bytecode := code.code
+	ip := frame.ip
+
+	for {
+		op := opcode(bytecode[ip])
+		ip++
+		switch op {
+		case opConstant:
+			at := operand(bytecode, ip)
+			ip += 2
+			stack.push(code.constants[at])
+		case opPop:
+			stack.pop()
+		case opGetLocal:
+			depth, slot, node := operand(bytecode, ip), operand(bytecode, ip+2), operand(bytecode, ip+4)
+			ip += 6
+			vars := intptr.env.vars
+			if i := len(vars) - 1 - depth; i >= 0 && slot < len(vars[i].store.values) {
+				stack.push(vars[i].store.values[slot])
+				break
+			}
+			val, err := intptr.variable(code.nodes[node].(Variable), false)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opSetLocal:
+			depth, slot, node := operand(bytecode, ip), operand(bytecode, ip+2), operand(bytecode, ip+4)
+			ip += 6
+			vars := intptr.env.vars
+			if i := len(vars) - 1 - depth; i >= 0 && slot < len(vars[i].store.values) {
+				vars[i].store.values[slot] = stack.pop()
+				break
+			}
+			stmt, val := code.nodes[node].(AssignmentStatement), stack.pop()
+			if err := intptr.env.varAssign(stmt.Identifier, stmt.local, val); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opGetGlobal:
+			node, callee := operand(bytecode, ip), operand(bytecode, ip+2)
+			ip += 4
+			global := intptr.env.vars[0].store
+			if slot := code.globals[node]; slot >= 0 {
+				stack.push(global.values[slot])
+				break
+			}
+			variable := code.nodes[node].(Variable)
+			if slot, found := global.slot(variable.identifier.Lexeme); found {
+				code.globals[node] = slot
+				stack.push(global.values[slot])
+				break
+			}
+			val, err := intptr.variable(variable, callee == 1)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opSetGlobal:
+			node := operand(bytecode, ip)
+			ip += 2
+			global := intptr.env.vars[0].store
+			if slot := code.globals[node]; slot >= 0 {
+				global.values[slot] = stack.pop()
+				break
+			}
+			stmt, val := code.nodes[node].(AssignmentStatement), stack.pop()
+			if slot, found := global.slot(stmt.Identifier.Lexeme); found {
+				code.globals[node] = slot
+			}
+			if err := intptr.env.varAssign(stmt.Identifier, stmt.local, val); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opGetName:
+			node, callee := operand(bytecode, ip), operand(bytecode, ip+2)
+			ip += 4
+			val, err := intptr.variable(code.nodes[node].(Variable), callee == 1)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opSetName:
+			at := operand(bytecode, ip)
+			ip += 2
+			stmt, val := code.nodes[at].(AssignmentStatement), stack.pop()
+			if err := intptr.env.varAssign(stmt.Identifier, stmt.local, val); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opDeclare:
+			at := operand(bytecode, ip)
+			ip += 2
+			name, val := code.constants[at].asString(), stack.pop()
+			intptr.env.varStore(name, val)
+		case opPushBlock:
+			at := operand(bytecode, ip)
+			ip += 2
+			vars := newVarMap()
+			if n := len(intptr.varMaps); n > 0 && !code.captures {
+				vars, intptr.varMaps = intptr.varMaps[n-1], intptr.varMaps[:n-1]
+			}
+			frame.push(intptr, Block{code.constants[at].asString(), vars})
+		case opPopBlock:
+			count := operand(bytecode, ip)
+			ip += 2
+			blocks := len(intptr.env.vars) - count
+			// Nothing can still see the blocks unless they were captured, so they can be pushed again
+			if !code.captures {
+				for _, block := range intptr.env.vars[blocks:] {
+					block.store.reset(block.store.names[:0])
+					intptr.varMaps = append(intptr.varMaps, block.store)
+				}
+			}
+			frame.truncate(intptr, blocks)
+		case opJump:
+			ip = operand(bytecode, ip)
+		case opJumpIfFalse:
+			target := operand(bytecode, ip)
+			ip += 2
+			if !stack.pop().truthy() {
+				ip = target
+			}
+		case opJumpIfTrueOrPop:
+			target := operand(bytecode, ip)
+			ip += 2
+			if stack.top().truthy() {
+				ip = target
+				break
+			}
+			stack.pop()
+		case opJumpIfFalseOrPop:
+			target := operand(bytecode, ip)
+			ip += 2
+			if !stack.top().truthy() {
+				ip = target
+				break
+			}
+			stack.pop()
+		case opAdd, opSubtract, opMultiply, opDivide, opLess, opGreater, opBinary:
+			node := operand(bytecode, ip)
+			ip += 2
+			right, left := stack.pop(), stack.pop()
+			if val, ok := arithmetic(op, left, right); ok {
+				stack.push(val)
+				break
+			}
+			val, err := code.nodes[node].(Binary).apply(intptr, left, right)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opNegate, opIncrement, opDecrement, opUnary:
+			node := operand(bytecode, ip)
+			ip += 2
+			if top := stack.top(); top.kind == IntKind && op != opUnary {
+				stack.pop()
+				switch op {
+				case opNegate:
+					stack.push(intValue(-top.asInt()))
+				case opIncrement:
+					stack.push(intValue(top.asInt() + 1))
+				case opDecrement:
+					stack.push(intValue(top.asInt() - 1))
+				}
+				break
+			}
+			val, err := code.nodes[node].(Unary).apply(stack.pop())
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opCall:
+			count, node := operand(bytecode, ip), operand(bytecode, ip+2)
+			ip += 4
+			// Calls copy their arguments before anything else is pushed, so they can stay on the stack
+			args := stack.popN(count)
+			val, err := intptr.callValue(stack.pop(), code.nodes[node].(Token), args)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opCheckInvoke:
+			at := operand(bytecode, ip)
+			ip += 2
+			if err := code.nodes[at].(MethodInvocation).check(stack.top()); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opInvoke:
+			count, node := operand(bytecode, ip), operand(bytecode, ip+2)
+			ip += 4
+			args := stack.popN(count)
+			val, err := stack.pop().asInstance().invoke(intptr, code.nodes[node].(MethodInvocation).identifier, args)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opGetProperty:
+			at := operand(bytecode, ip)
+			ip += 2
+			val, err := code.nodes[at].(PropertyAccess).access(intptr, stack.pop())
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opCheckProperty:
+			at := operand(bytecode, ip)
+			ip += 2
+			if err := code.nodes[at].(PropertyAssignmentStatement).check(stack.top()); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opSetProperty:
+			at := operand(bytecode, ip)
+			ip += 2
+			stmt, val := code.nodes[at].(PropertyAssignmentStatement), stack.pop()
+			stack.pop().asInstance().scope.varStore(stmt.get.identifier.Lexeme, val)
+		case opArray:
+			count := operand(bytecode, ip)
+			ip += 2
+			vals := make([]Value, count)
+			copy(vals, stack.popN(count))
+			stack.push(arrayValue(vals))
+		case opMap:
+			stack.push(mapValue(newJlangMap()))
+		case opCheckKey:
+			node := operand(bytecode, ip)
+			ip += 2
+			if err := checkKey(stack.top()); err != nil {
+				return frame.fail(stack, BadIndex{spanOf(code.nodes[node].(Expression)), err})
+			}
+		case opMapSet:
+			val, key := stack.pop(), stack.pop()
+			stack.top().asMap().set(key, val)
+		case opIndex:
+			node := operand(bytecode, ip)
+			ip += 2
+			index, container := stack.pop(), stack.pop()
+			if err := code.nodes[node].(ArrayAccess).check(container, index); err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(get(container, index))
+		case opCheckIndex:
+			at := operand(bytecode, ip)
+			ip += 2
+			if err := code.nodes[at].(ArrayAccess).check(stack[len(stack)-2], stack[len(stack)-1]); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opSetIndex:
+			val, index, container := stack.pop(), stack.pop(), stack.pop()
+			set(container, index, val)
+		case opConcat:
+			count := operand(bytecode, ip)
+			ip += 2
+			str := strings.Builder{}
+			for _, val := range stack.popN(count) {
+				str.WriteString(val.String())
+			}
+			stack.push(stringValue(str.String()))
+		case opClosure:
+			at := operand(bytecode, ip)
+			ip += 2
+			fun := code.nodes[at].(JlangFunction)
+			fun.closure = intptr.env.capture()
+			stack.push(functionValue(fun))
+		case opClass:
+			at := operand(bytecode, ip)
+			ip += 2
+			class := code.nodes[at].(compiledClass)
+			if err := class.class.declare(intptr, class.methods); err != nil {
+				return frame.fail(stack, err)
+			}
+		case opPrint:
+			intptr.writeLog.Println(stack.pop().String())
+		case opThrow:
+			node := operand(bytecode, ip)
+			ip += 2
+			return frame.fail(stack, code.nodes[node].(ThrowStatement).throw(stack.pop()))
+		case opTry, opFinally:
+			target := operand(bytecode, ip)
+			ip += 2
+			frame.handlers = append(frame.handlers, vmHandler{target, len(stack), len(intptr.env.vars), op == opTry})
+		case opEndTry:
+			frame.handlers = frame.handlers[:len(frame.handlers)-1]
+		case opRethrow:
+			rethrow := frame.pending[len(frame.pending)-1]
+			frame.pending = frame.pending[:len(frame.pending)-1]
+			intptr.failed = rethrow.failed
+			return frame.fail(stack, rethrow.err)
+		case opReturn:
+			frame.truncate(intptr, frame.blocks)
+			val := stack.pop()
+			frame.stack = stack
+			return val, nil
+		case opSuper:
+			node := operand(bytecode, ip)
+			ip += 2
+			this, superclass := stack.pop(), stack.pop()
+			val, err := code.nodes[node].(SuperAccess).bind(superclass, this)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opEval:
+			at := operand(bytecode, ip)
+			ip += 2
+			val, err := code.nodes[at].(Expression).evaluate(intptr)
+			if err != nil {
+				return frame.fail(stack, err)
+			}
+			stack.push(val)
+		case opExec:
+			at := operand(bytecode, ip)
+			ip += 2
+			if err := code.nodes[at].(Statement).execute(intptr); err != nil {
+				return frame.fail(stack, err)
+			}
+		default:
+			return frame.fail(stack, InternalError{12, fmt.Sprintf("unknown opcode %d", op)})
+		}
+	}
+}
+
+// vmStack is the operands of the code being run
+type vmStack []Value
+
+func (stack *vmStack) push(val Value) {
+	*stack = append(*stack, val)
+}
+
+func (stack *vmStack) pop() Value {
+	val := (*stack)[len(*stack)-1]
+	*stack = (*stack)[:len(*stack)-1]
+	return val
+}
+
+func (stack vmStack) top() Value {
+	return stack[len(stack)-1]
+}
+
+// popN pops the top count values, which are only valid until the next push
+func (stack *vmStack) popN(count int) []Value {
+	vals := (*stack)[len(*stack)-count:]
+	*stack = (*stack)[:len(*stack)-count]
+	return vals
+}
+
+// operand is the operand of an instruction at
+func operand(code []byte, at int) int {
+	return int(code[at])<<8 | int(code[at+1])
+}
+
+// variable looks a Variable up by name, an unknown callee is a BadCall the same as when it's walked
+func (intptr *Interpreter) variable(variable Variable, callee bool) (Value, error) {
+	val, err := intptr.VariableResolver(variable)
+	if unknown, ok := err.(UnknownIdentifier); ok && callee {
+		return Value{}, BadCall{unknown.Token, nil}
+	}
+	return val, err
+}
+
+// arithmetic is the common case of a Binary on two ints or two numbers, which is everything that isn't
+// an error or a conversion. Anything else isn't ok and falls back to Binary.apply.
+func arithmetic(op opcode, left Value, right Value) (Value, bool) {
+	if left.kind != right.kind {
+		return Value{}, false
+	}
+	switch left.kind {
+	case IntKind:
+		l, r := left.asInt(), right.asInt()
+		switch op {
+		case opAdd:
+			return intValue(l + r), true
+		case opSubtract:
+			return intValue(l - r), true
+		case opMultiply:
+			return intValue(l * r), true
+		case opDivide:
+			if l != 0 && r != 0 {
+				return intValue(l / r), true
+			}
+		case opLess:
+			return boolValue(l < r), true
+		case opGreater:
+			return boolValue(l > r), true
+		}
+	case NumberKind:
+		l, r := left.asNumber(), right.asNumber()
+		switch op {
+		case opAdd:
+			return numberValue(l + r), true
+		case opSubtract:
+			return numberValue(l - r), true
+		case opMultiply:
+			return numberValue(l * r), true
+		case opDivide:
+			return numberValue(l / r), true
+		case opLess:
+			return boolValue(l < r), true
+		case opGreater:
+			return boolValue(l > r), true
+		}
+	}
+	return Value{}, false
+}
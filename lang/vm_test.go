@@ -0,0 +1,137 @@
+package lang
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// runEngines runs text with the tree walker and then the VM, returning what each printed followed by its error
+func runEngines(text string) (string, string) {
+	// Printed instances show their addresses, which change run to run
+	addresses := regexp.MustCompile("0x[0-9a-f]+")
+	run := func(options ...Option) string {
+		out := strings.Builder{}
+		intptr := NewInterpreter(options...)
+		intptr.HookLogOut(&out)
+		intptr.HookWarnOut(&strings.Builder{})
+		if err := intptr.Interpret(text); err != nil {
+			out.WriteString(err.Error())
+		}
+		return addresses.ReplaceAllString(out.String(), "0x")
+	}
+	return run(), run(WithVM())
+}
+
+func TestVMTests(t *testing.T) {
+	files, err := filepath.Glob("../tests/*.jlang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := openFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if walked, compiled := runEngines(*src); walked != compiled {
+			t.Errorf("%s ran differently on the VM:\n%s\nwant:\n%s", file, compiled, walked)
+		}
+	}
+}
+
+func TestVM(t *testing.T) {
+	inputs := []string{
+		// Jumping out of loops drops the blocks jumped out of
+		"for var i = 0; i < 5; i = ++i {\n var j = i * 2;\n if j > 4 { var k = j; print k; break; }\n" +
+			" for var n = 0; n < 3; n = ++n { var m = n; if m == 1 { continue; } print i + m; }\n}\nprint pi;",
+		"var i = 0;\nwhile i < 10 { i = i + 1; if i % 2 == 0 { continue; } if i > 7 { break; } print i; }\nprint i;",
+		// Short circuiting and the value that decided it
+		"print nil or \"default\";\nprint false and 1 / 0;\nprint true and 2;\nprint 1 > 0 || 1 / 0 == 0;",
+		// Mixed and unusual operands fall back to the tree walker's rules
+		"print 1 + 2.5; print \"a\" + 1; print 1 + \"a\"; print 7 / 2; print 7.0 / 2; print 3 % 2; print -(2.5); print !true; print true + true;",
+		"print 1 >= 1; print 2 <= 1.5; print nil == nil; print 1 != nil;",
+		"print 0 / 1;",
+		"print 1 / 0;",
+		"print 1 - \"a\";",
+		"print nil + 1;",
+		// Closures and classes made by the VM work with the ones made by walking
+		"func counter() { var n = 0; return () => { n = n + 1; return n; }; }\nvar c = counter(); c(); print c();\nprint map([1, 2], x => x * 10);",
+		"class A { var x = 1; func A(y) { this.x = this.x + y; } func get() { return this.x; } }\n" +
+			"class B < A { func B() { super(10); } func get() { return super.get() * 2; } }\nprint B().get(); print A(1).x;",
+		// The blocks of calls are used again once they return, but never while a call is still in them
+		"func fib(n) { var a = n; if n < 2 { return a; } var b = fib(n - 1) + fib(n - 2); return b; }\nprint fib(10); print fib(3);",
+		"func f(n) { try { if n > 0 { var x = n; f(n - 1); print x; } else { throw Error(\"bottom\"); } } catch (e) { print e.message; }" +
+			" var y = n; return y; }\nprint f(2); print map([1, 2], f);",
+		"var m = {\"a\": [1, {\"b\": 2}]};\nm[\"a\"][1][\"b\"] = 3;\nprint m;\nprint \"${m[\"a\"][0]} and ${len(m)}\";",
+		"var m = {\"a\": 1, [2]: 2};",
+		"var a = [1];\na[1] = 2;",
+		"undefined();",
+		"func f() { return g(); }\nprint f();\nfunc g() { return 1; }",
+		"var x = 1;\nx.y = 2;",
+		"var x = 1;\nx.y();",
+	}
+	for _, input := range inputs {
+		if walked, compiled := runEngines(input); walked != compiled {
+			t.Errorf("%q ran differently on the VM:\n%s\nwant:\n%s", input, compiled, walked)
+		}
+	}
+}
+
+// TestVMError makes sure the scope is the same after an error as it was before, so the REPL can carry on
+func TestVMError(t *testing.T) {
+	intptr := NewInterpreter(WithVM())
+	out := strings.Builder{}
+	intptr.HookLogOut(&out)
+	if err := intptr.Interpret("var x = 1;\nfor var i = 0; i < 3; i = ++i { var y = i; if y == 1 { print [][0]; } }"); err == nil {
+		t.Fatal("expected an OutOfBounds error")
+	} else if reflect.TypeOf(err).Name() != "OutOfBounds" {
+		t.Fatalf("expected an OutOfBounds error, got %v", err)
+	}
+	if len(intptr.env.vars) != 1 {
+		t.Fatalf("expected only the global block after the error, got %d blocks", len(intptr.env.vars))
+	}
+	if err := intptr.Interpret("print x;"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n" {
+		t.Errorf("expected 1, got %q", out.String())
+	}
+}
+
+func BenchmarkLoop(b *testing.B) {
+	benchmarkEngines(b, "var sum = 0;\nfor var i = 0; i < 100000; i = ++i { sum = sum + i * 2; }")
+}
+
+// BenchmarkHadamard calls the hadamard product from /tests/ over and over, which is loops, indexing and builtin calls
+func BenchmarkHadamard(b *testing.B) {
+	text, err := ioutil.ReadFile("../tests/hadamard.jlang")
+	if err != nil {
+		b.Fatal(err)
+	}
+	loop := "for var n = 0; n < 5000; n = ++n { hadamard(vec, T); }"
+	benchmarkEngines(b, strings.Replace(string(text), "print hadamard(vec, T);", loop, 1))
+}
+
+// BenchmarkCalls is mostly calling functions
+func BenchmarkCalls(b *testing.B) {
+	benchmarkEngines(b, "func fib(n) { if n < 2 { return n; } return fib(n - 1) + fib(n - 2); }\nfib(20);")
+}
+
+// benchmarkEngines runs src with the tree walker and the VM
+func benchmarkEngines(b *testing.B, src string) {
+	for _, engine := range []struct {
+		name    string
+		options []Option
+	}{{"walk", nil}, {"vm", []Option{WithVM()}}} {
+		b.Run(engine.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				if err := NewInterpreter(engine.options...).Interpret(src); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
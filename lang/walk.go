@@ -0,0 +1,229 @@
+package lang
+
+import "reflect"
+
+// Node is any node of a parsed program, the *Program itself or one of its Statements or Expressions
+type Node interface{}
+
+// A Visitor's Visit is called for each Node Walk comes to. If it returns a non nil Visitor w, Walk visits each of
+// the node's children with w and then calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, the same way go/ast.Walk does.
+// Children are visited in the order of the node's fields, which is source order for everything but class members.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, field := range Fields(node) {
+		switch value := field.Value.(type) {
+		case nil, Token, []Token, string:
+		case []Node:
+			for _, child := range value {
+				Walk(v, child)
+			}
+		default:
+			Walk(v, value)
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order like Walk, calling f for each node and then f(nil) after its children.
+// The children of a node are skipped when f returns false for it.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Field is a named part of a node. Value is a Node, []Node, Token, []Token, string or nil when the node doesn't have that part.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Fields is every part of a node in source order, which is how tools outside the package get at a node's
+// unexported fields. Nodes without any parts, like the builtins, have no Fields and unknown nodes have nil.
+func Fields(node Node) []Field {
+	switch n := node.(type) {
+	case *Program:
+		return []Field{{"statements", statementNodes(n.Statements)}}
+	case Program:
+		return Fields(&n)
+	case VariableStatement:
+		return []Field{{"name", n.Identifier}, {"value", expressionNode(n.Expr)}}
+	case AssignmentStatement:
+		return []Field{{"name", n.Identifier}, {"value", expressionNode(n.Expr)}}
+	case FunctionDeclarationStatement:
+		return []Field{{"name", n.Identifier}, {"doc", n.Doc}, {"params", parameterTokens(n)}, {"body", statementNodes(n.block)}}
+	case PropertyAssignmentStatement:
+		return []Field{{"target", n.get}, {"value", expressionNode(n.value)}}
+	case IndexAssignmentStatement:
+		return []Field{{"target", n.get}, {"value", expressionNode(n.value)}}
+	case IfStatement:
+		var elseBlock interface{}
+		if n.elseBlock != nil {
+			elseBlock = statementNodes(*n.elseBlock)
+		}
+		return []Field{{"condition", expressionNode(n.Expr)}, {"then", statementNodes(n.block)}, {"else", elseBlock}}
+	case WhileStatement:
+		return []Field{{"condition", expressionNode(n.test)}, {"body", statementNodes(n.block)}}
+	case ForStatement:
+		var init interface{}
+		if n.varStmt != nil {
+			init = *n.varStmt
+		}
+		return []Field{{"init", init}, {"condition", expressionNode(n.test)}, {"update", n.assign}, {"body", statementNodes(n.block)}}
+	case BreakStatement:
+		return []Field{{"keyword", n.keyword}}
+	case ContinueStatement:
+		return []Field{{"keyword", n.keyword}}
+	case ThrowStatement:
+		return []Field{{"keyword", n.keyword}, {"value", expressionNode(n.Expression)}}
+	case TryStatement:
+		var param, catchBlock, finallyBlock interface{}
+		if n.catchBlock != nil {
+			param, catchBlock = *n.catchParam, statementNodes(*n.catchBlock)
+		}
+		if n.finallyBlock != nil {
+			finallyBlock = statementNodes(*n.finallyBlock)
+		}
+		return []Field{{"keyword", n.keyword}, {"body", statementNodes(n.block)}, {"param", param}, {"catch", catchBlock}, {"finally", finallyBlock}}
+	case ExpressionStatement:
+		return []Field{{"expression", expressionNode(n.Expression)}}
+	case ReturnStatement:
+		if isBareReturn(n) {
+			return []Field{{"value", nil}}
+		}
+		return []Field{{"value", expressionNode(n.Expression)}}
+	case PrintStatement:
+		return []Field{{"value", expressionNode(n.Expression)}}
+	case JlangClass:
+		var superclass interface{}
+		if n.Stmt.superclass != nil {
+			superclass = *n.Stmt.superclass
+		}
+		return []Field{{"name", n.identifier}, {"doc", n.Doc}, {"superclass", superclass}, {"members", classMembers(n)}}
+	case Binary:
+		return []Field{{"left", expressionNode(n.Left)}, {"operator", n.Op.Token}, {"right", expressionNode(n.Right)}}
+	case Logical:
+		return []Field{{"left", expressionNode(n.Left)}, {"operator", n.Op.Token}, {"right", expressionNode(n.Right)}}
+	case Grouping:
+		return []Field{{"expression", expressionNode(n.Expr)}}
+	case Unary:
+		return []Field{{"operator", n.Op.Token}, {"operand", expressionNode(n.Expr)}}
+	case Literal:
+		return []Field{{"value", n.Token}}
+	case Variable:
+		return []Field{{"name", n.identifier}}
+	case Interpolation:
+		return []Field{{"parts", expressionNodes(&n.parts)}}
+	case Call:
+		return []Field{{"callee", expressionNode(n.callee)}, {"arguments", expressionNodes(n.args)}}
+	case Lambda:
+		return []Field{{"params", parameterTokens(n.decl)}, {"body", statementNodes(n.decl.block)}}
+	case SuperAccess:
+		var method interface{}
+		if n.method != nil {
+			method = *n.method
+		}
+		return []Field{{"keyword", n.keyword}, {"method", method}}
+	case MethodInvocation:
+		return []Field{{"receiver", expressionNode(n.this)}, {"method", n.identifier}, {"arguments", expressionNodes(n.argExprs)}}
+	case PropertyAccess:
+		return []Field{{"object", expressionNode(n.Expr)}, {"property", n.identifier}}
+	case ArrayLiteral:
+		return []Field{{"elements", expressionNodes(&n.elements)}}
+	case MapLiteral:
+		return []Field{{"keys", expressionNodes(&n.keys)}, {"values", expressionNodes(&n.values)}}
+	case ArrayAccess:
+		return []Field{{"object", expressionNode(n.Expr)}, {"index", expressionNode(n.index)}}
+	case Len, Time, Pow, Quit, AppendBuiltin, MapBuiltin, Keys, Values, Has, Delete, Locals, ErrorInit:
+		// Builtins get their arguments from the scope of the call to them
+		return []Field{}
+	}
+	return nil
+}
+
+// Kind is the name of a node's type i.e 'Binary' or 'IfStatement'
+func Kind(node Node) string {
+	t := reflect.TypeOf(node)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Span is the source an Expression was parsed from, from its first through its last token.
+// Expressions that weren't parsed from a source, like the builtins, have an empty Token.
+func Span(expr Expression) Token {
+	return spanOf(expr)
+}
+
+// expressionNode keeps a missing expression a nil interface{} instead of a nil Expression
+func expressionNode(expr Expression) interface{} {
+	if expr == nil {
+		return nil
+	}
+	return expr
+}
+
+func expressionNodes(exprs *[]Expression) []Node {
+	nodes := make([]Node, 0)
+	if exprs != nil {
+		for _, expr := range *exprs {
+			nodes = append(nodes, expr)
+		}
+	}
+	return nodes
+}
+
+func statementNodes(stmts []Statement) []Node {
+	nodes := make([]Node, len(stmts))
+	for i, stmt := range stmts {
+		nodes[i] = stmt
+	}
+	return nodes
+}
+
+// parameterTokens is the parameters of a function, without the 'this' the parser gives methods
+func parameterTokens(decl FunctionDeclarationStatement) []Token {
+	params := make([]Token, 0)
+	if decl.args != nil {
+		for _, arg := range *decl.args {
+			if !arg.is(This) {
+				params = append(params, arg)
+			}
+		}
+	}
+	return params
+}
+
+// classMembers is the member variables, constructor and methods of a class, in that order
+func classMembers(class JlangClass) []Node {
+	members := make([]Node, 0)
+	if class.Stmt.varDecls != nil {
+		for _, varDecl := range *class.Stmt.varDecls {
+			members = append(members, varDecl)
+		}
+	}
+	if class.Stmt.constructor != nil {
+		members = append(members, *class.Stmt.constructor)
+	}
+	if class.Stmt.funcDecls != nil {
+		for _, funcDecl := range *class.Stmt.funcDecls {
+			members = append(members, funcDecl)
+		}
+	}
+	return members
+}
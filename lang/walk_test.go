@@ -0,0 +1,75 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+)
+
+// kindCollector records the kind of every node it visits
+type kindCollector struct {
+	kinds []string
+}
+
+func (c *kindCollector) Visit(node Node) Visitor {
+	if node != nil {
+		c.kinds = append(c.kinds, Kind(node))
+	}
+	return c
+}
+
+func TestWalk(t *testing.T) {
+	program, err := parseSource(t, "func f(a) { return a + 1; }\nprint [f(2), {\"k\": x}];\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &kindCollector{}
+	Walk(collector, program)
+	expected := []string{
+		"Program",
+		"FunctionDeclarationStatement", "ReturnStatement", "Binary", "Variable", "Literal",
+		"PrintStatement", "ArrayLiteral", "Call", "Variable", "Literal", "MapLiteral", "Literal", "Variable",
+	}
+	if !reflect.DeepEqual(collector.kinds, expected) {
+		t.Errorf("expected to visit %v, got %v", expected, collector.kinds)
+	}
+}
+
+// TestWalkEveryNode walks a program with every kind of node in it, along with the builtins, and makes sure Fields knows them all
+func TestWalkEveryNode(t *testing.T) {
+	input := "" +
+		"class A { var x; func A(x) { this.x = x; } func f() { return this.x; } }\n" +
+		"class B < A { func f() { return super.f(); } func g() { super(1); } }\n" +
+		"var arr = [1, 2];\n" +
+		"arr[0] = -arr[1];\n" +
+		"var m = {\"k\": (1 + 2) * 3};\n" +
+		"m = B(1);\n" +
+		"m.x = m.f();\n" +
+		"for var i = 0; i < 2 and true; i = i + 1 { if i == 0 { continue; } else { break; } }\n" +
+		"while false { print \"${m.x}\"; }\n" +
+		"var f = func() { return; };\n" +
+		"var g = x => x;\n" +
+		"g(f);\n" +
+		"try { throw Error(\"x\"); } catch (e) { print e; } finally { print 1; }\n"
+	program, err := parseSource(t, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string]bool)
+	for _, node := range []Node{program, &Program{globals()}} {
+		Inspect(node, func(node Node) bool {
+			if node == nil {
+				return false
+			}
+			if Fields(node) == nil {
+				t.Errorf("Fields doesn't know %s", Kind(node))
+			}
+			kinds[Kind(node)] = true
+			return true
+		})
+	}
+	if len(kinds) != 44 {
+		t.Errorf("expected to come across all 44 kinds of node, got %d: %v", len(kinds), kinds)
+	}
+}
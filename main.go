@@ -5,12 +5,23 @@ import (
 	"fmt"
 	"github.com/jntun/mylang/lang"
 	"os"
+	"strings"
 )
 
-var interpreter = lang.NewInterpreter()
+var (
+	options     []lang.Option // How every interpreter is made, from the flags that pick the engine
+	interpreter = lang.NewInterpreter()
+)
 
 func main() {
 	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "fmt" || args[0] == "-fmt") {
+		os.Exit(formatFiles(args[1:]))
+	}
+	if len(args) > 0 && strings.HasPrefix(args[0], "-ast") {
+		os.Exit(dumpAST(args[0], args[1:]))
+	}
+	args = engineArgs(args)
 	if len(args) < 1 {
 		popInterpreter()
 	}
@@ -84,6 +95,20 @@ func InvalidInput(err error) {
 	}
 }
 
+// engineArgs takes the flags that pick how scripts run out of args, i.e '-vm' to run them on the bytecode VM
+func engineArgs(args []string) []string {
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "-vm" {
+			options = append(options, lang.WithVM())
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	interpreter = lang.NewInterpreter(options...)
+	return rest
+}
+
 func processArgs(args []string) {
 	for _, arg := range args {
 		if arg == "-server" {
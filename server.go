@@ -95,7 +95,7 @@ func runScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Println("/jlang:", string(data))
-	intptr := jlang.NewInterpreter()
+	intptr := jlang.NewInterpreter(options...)
 
 	err = intptr.HookLogOut(w)
 	if err != nil {